@@ -0,0 +1,499 @@
+package appointmenttypes
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"emr-calendar-backend/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultDurationMinutes is used when an appointment type is created without a duration
+const defaultDurationMinutes = 30
+
+const appointmentTypeColumns = `id, user_id, team_id, name, duration_minutes, buffer_before_minutes,
+	buffer_after_minutes, color, schedule_id, created_at, updated_at`
+
+type AppointmentTypesHandler struct {
+	db *sql.DB
+}
+
+func NewAppointmentTypesHandler(db *sql.DB) *AppointmentTypesHandler {
+	return &AppointmentTypesHandler{
+		db: db,
+	}
+}
+
+// GetAppointmentTypes lists the appointment types a provider can be booked for (the current
+// user's by default): their own and those of their teams. With team_id, lists the team's types.
+func (ah *AppointmentTypesHandler) GetAppointmentTypes(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if teamID := c.Query("team_id"); teamID != "" {
+		ah.getTeamTypes(c, userCtx, teamID)
+		return
+	}
+
+	providerID := c.DefaultQuery("provider_id", userCtx.UserID)
+
+	// Staff may only view providers within their own teams
+	allowed, err := userCtx.CanAccessProvider(ah.db, providerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
+		return
+	}
+
+	query := `
+		SELECT ` + appointmentTypeColumns + `
+		FROM appointment_types
+		WHERE user_id = $1 OR team_id IN (SELECT team_id FROM providers WHERE user_id = $1)
+		ORDER BY name ASC`
+
+	ah.listTypes(c, query, providerID)
+}
+
+// getTeamTypes lists the appointment types offered by a team. Staff must belong to the team.
+func (ah *AppointmentTypesHandler) getTeamTypes(c *gin.Context, userCtx *auth.UserContext, teamID string) {
+	if userCtx.IsStaff() {
+		role, err := ah.getTeamRole(teamID, userCtx.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check team membership"})
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
+	}
+
+	query := `
+		SELECT ` + appointmentTypeColumns + `
+		FROM appointment_types
+		WHERE team_id = $1
+		ORDER BY name ASC`
+
+	ah.listTypes(c, query, teamID)
+}
+
+// listTypes writes the appointment types selected by query
+func (ah *AppointmentTypesHandler) listTypes(c *gin.Context, query string, args ...interface{}) {
+	rows, err := ah.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment types", "details": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	appointmentTypes := []AppointmentType{}
+	for rows.Next() {
+		var appointmentType AppointmentType
+		if err := scanAppointmentType(rows, &appointmentType); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to scan appointment type: %v", err)})
+			return
+		}
+		appointmentTypes = append(appointmentTypes, appointmentType)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appointment_types": appointmentTypes,
+		"count":             len(appointmentTypes),
+	})
+}
+
+// CreateAppointmentType creates an appointment type for the current provider (or, for admins,
+// for a provider within their teams), or for a whole team when team_id is given
+func (ah *AppointmentTypesHandler) CreateAppointmentType(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if !userCtx.IsStaff() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only providers and admins can manage appointment types"})
+		return
+	}
+
+	var req CreateAppointmentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type name cannot be empty"})
+		return
+	}
+
+	var providerID, teamID *string
+	if req.TeamID != nil && *req.TeamID != "" {
+		if req.ProviderID != nil && *req.ProviderID != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set either provider_id or team_id, not both"})
+			return
+		}
+		if req.ScheduleID != nil && *req.ScheduleID != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Team appointment types always use each member's default schedule"})
+			return
+		}
+		if ok := ah.requireTeamAdmin(c, userCtx, *req.TeamID); !ok {
+			return
+		}
+		teamID = req.TeamID
+	} else if userCtx.UserRole == "admin" && req.ProviderID != nil && *req.ProviderID != "" {
+		allowed, err := userCtx.CanAccessProvider(ah.db, *req.ProviderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
+			return
+		}
+		providerID = req.ProviderID
+	} else {
+		providerID = &userCtx.UserID
+	}
+
+	if req.DurationMinutes == 0 {
+		req.DurationMinutes = defaultDurationMinutes
+	}
+
+	if req.ScheduleID != nil && *req.ScheduleID != "" {
+		if ok := ah.validateSchedule(c, *providerID, *req.ScheduleID); !ok {
+			return
+		}
+	} else {
+		req.ScheduleID = nil
+	}
+
+	query := `
+		INSERT INTO appointment_types (id, user_id, team_id, name, duration_minutes, buffer_before_minutes,
+		                               buffer_after_minutes, color, schedule_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + appointmentTypeColumns
+
+	var appointmentType AppointmentType
+	now := time.Now().UTC()
+	err := scanAppointmentType(ah.db.QueryRow(
+		query,
+		uuid.New().String(), providerID, teamID, strings.TrimSpace(req.Name), req.DurationMinutes,
+		req.BufferBeforeMinutes, req.BufferAfterMinutes, req.Color, req.ScheduleID, now, now,
+	), &appointmentType)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment type", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"appointment_type": appointmentType})
+}
+
+// GetAppointmentType retrieves a single appointment type
+func (ah *AppointmentTypesHandler) GetAppointmentType(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	appointmentType, err := ah.getAccessibleType(userCtx, c.Param("id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appointment_type": appointmentType})
+}
+
+// UpdateAppointmentType updates an appointment type, including its buffers, color and the schedule it is booked against
+func (ah *AppointmentTypesHandler) UpdateAppointmentType(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	typeID := c.Param("id")
+
+	var req UpdateAppointmentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	existingType, ok := ah.getManageableType(c, userCtx, typeID)
+	if !ok {
+		return
+	}
+
+	// Build dynamic update query
+	updateFields := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, fmt.Sprintf("name = $%d", argIndex))
+		args = append(args, strings.TrimSpace(*req.Name))
+		argIndex++
+	}
+
+	if req.DurationMinutes != nil {
+		updateFields = append(updateFields, fmt.Sprintf("duration_minutes = $%d", argIndex))
+		args = append(args, *req.DurationMinutes)
+		argIndex++
+	}
+
+	if req.BufferBeforeMinutes != nil {
+		updateFields = append(updateFields, fmt.Sprintf("buffer_before_minutes = $%d", argIndex))
+		args = append(args, *req.BufferBeforeMinutes)
+		argIndex++
+	}
+
+	if req.BufferAfterMinutes != nil {
+		updateFields = append(updateFields, fmt.Sprintf("buffer_after_minutes = $%d", argIndex))
+		args = append(args, *req.BufferAfterMinutes)
+		argIndex++
+	}
+
+	if req.Color != nil {
+		var color *string
+		if *req.Color != "" {
+			color = req.Color
+		}
+		updateFields = append(updateFields, fmt.Sprintf("color = $%d", argIndex))
+		args = append(args, color)
+		argIndex++
+	}
+
+	if req.ScheduleID != nil {
+		var scheduleID *string
+		if *req.ScheduleID != "" {
+			if existingType.TeamID != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Team appointment types always use each member's default schedule"})
+				return
+			}
+			if ok := ah.validateSchedule(c, *existingType.ProviderID, *req.ScheduleID); !ok {
+				return
+			}
+			scheduleID = req.ScheduleID
+		}
+		updateFields = append(updateFields, fmt.Sprintf("schedule_id = $%d", argIndex))
+		args = append(args, scheduleID)
+		argIndex++
+	}
+
+	if len(updateFields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	// Add updated_at field
+	updateFields = append(updateFields, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now().UTC())
+	argIndex++
+
+	args = append(args, typeID)
+	updateQuery := fmt.Sprintf(`
+		UPDATE appointment_types
+		SET %s
+		WHERE id = $%d
+		RETURNING %s`,
+		strings.Join(updateFields, ", "),
+		argIndex,
+		appointmentTypeColumns)
+
+	var updatedType AppointmentType
+	if err := scanAppointmentType(ah.db.QueryRow(updateQuery, args...), &updatedType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment type"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appointment_type": updatedType})
+}
+
+// DeleteAppointmentType deletes an appointment type
+func (ah *AppointmentTypesHandler) DeleteAppointmentType(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	typeID := c.Param("id")
+
+	if _, ok := ah.getManageableType(c, userCtx, typeID); !ok {
+		return
+	}
+
+	if _, err := ah.db.Exec(`DELETE FROM appointment_types WHERE id = $1`, typeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete appointment type"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Appointment type deleted successfully"})
+}
+
+// getAccessibleType loads an appointment type of a provider or team within the caller's tenant,
+// returning sql.ErrNoRows otherwise
+func (ah *AppointmentTypesHandler) getAccessibleType(userCtx *auth.UserContext, typeID string) (*AppointmentType, error) {
+	scope, scopeArgs := userCtx.ProviderScope("user_id", 2)
+	memberScope, memberArgs := userCtx.ProviderScope("user_id", 2+len(scopeArgs))
+	query := `
+		SELECT ` + appointmentTypeColumns + `
+		FROM appointment_types
+		WHERE id = $1 AND (` + scope + ` OR team_id IN (SELECT team_id FROM providers WHERE ` + memberScope + `))`
+	args := append(append([]interface{}{typeID}, scopeArgs...), memberArgs...)
+
+	var appointmentType AppointmentType
+	if err := scanAppointmentType(ah.db.QueryRow(query, args...), &appointmentType); err != nil {
+		return nil, err
+	}
+	return &appointmentType, nil
+}
+
+// getManageableType loads an appointment type the caller may modify: their own, or any
+// within their teams for admins; team types need a team admin. Writes the error response
+// and returns false otherwise.
+func (ah *AppointmentTypesHandler) getManageableType(c *gin.Context, userCtx *auth.UserContext, typeID string) (*AppointmentType, bool) {
+	appointmentType, err := ah.getAccessibleType(userCtx, typeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+		return nil, false
+	}
+
+	if appointmentType.TeamID != nil {
+		if ok := ah.requireTeamAdmin(c, userCtx, *appointmentType.TeamID); !ok {
+			return nil, false
+		}
+		return appointmentType, true
+	}
+
+	if *appointmentType.ProviderID != userCtx.UserID && userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the provider or an admin can modify this appointment type"})
+		return nil, false
+	}
+
+	return appointmentType, true
+}
+
+// requireTeamAdmin checks the caller is a member of the team with its admin role (or is an admin).
+// Writes the error response and returns false otherwise.
+func (ah *AppointmentTypesHandler) requireTeamAdmin(c *gin.Context, userCtx *auth.UserContext, teamID string) bool {
+	role, err := ah.getTeamRole(teamID, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check team membership"})
+		return false
+	}
+
+	// Hide the team entirely from non-members
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return false
+	}
+
+	if role != "admin" && userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Team admin role required"})
+		return false
+	}
+
+	return true
+}
+
+// getTeamRole returns the user's role in the team, or an empty string if they are not a member
+func (ah *AppointmentTypesHandler) getTeamRole(teamID, userID string) (string, error) {
+	var role string
+	err := ah.db.QueryRow(`SELECT role FROM providers WHERE team_id = $1 AND user_id = $2`, teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// validateSchedule checks that the schedule belongs to the provider offering the appointment type.
+// Writes the error response and returns false otherwise.
+func (ah *AppointmentTypesHandler) validateSchedule(c *gin.Context, providerID, scheduleID string) bool {
+	var exists bool
+	err := ah.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM schedules WHERE id = $1 AND user_id = $2)`,
+		scheduleID, providerID,
+	).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify schedule"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schedule does not belong to this provider"})
+		return false
+	}
+	return true
+}
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Load returns the appointment type with the given ID, or sql.ErrNoRows
+func Load(db Queryer, typeID string) (*AppointmentType, error) {
+	query := `SELECT ` + appointmentTypeColumns + ` FROM appointment_types WHERE id = $1`
+
+	var appointmentType AppointmentType
+	if err := scanAppointmentType(db.QueryRow(query, typeID), &appointmentType); err != nil {
+		return nil, err
+	}
+	return &appointmentType, nil
+}
+
+// ForProvider returns an appointment type the provider can be booked for: one of their own or
+// one offered by a team they belong to. It returns sql.ErrNoRows for any other type.
+func ForProvider(db Queryer, typeID, providerID string) (*AppointmentType, error) {
+	query := `
+		SELECT ` + appointmentTypeColumns + `
+		FROM appointment_types
+		WHERE id = $1 AND (user_id = $2 OR team_id IN (SELECT team_id FROM providers WHERE user_id = $2))`
+
+	var appointmentType AppointmentType
+	if err := scanAppointmentType(db.QueryRow(query, typeID, providerID), &appointmentType); err != nil {
+		return nil, err
+	}
+	return &appointmentType, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAppointmentType scans a row selected with appointmentTypeColumns
+func scanAppointmentType(row rowScanner, appointmentType *AppointmentType) error {
+	return row.Scan(
+		&appointmentType.ID, &appointmentType.ProviderID, &appointmentType.TeamID, &appointmentType.Name,
+		&appointmentType.DurationMinutes, &appointmentType.BufferBeforeMinutes, &appointmentType.BufferAfterMinutes,
+		&appointmentType.Color, &appointmentType.ScheduleID, &appointmentType.CreatedAt, &appointmentType.UpdatedAt,
+	)
+}
@@ -0,0 +1,45 @@
+package appointmenttypes
+
+import (
+	"time"
+)
+
+// AppointmentType represents a bookable service (e.g. "Telehealth follow-up") offered by a
+// single provider or by every member of a team
+type AppointmentType struct {
+	ID                  string    `json:"id" db:"id"`
+	ProviderID          *string   `json:"provider_id,omitempty" db:"user_id"` // Set for a provider's own type
+	TeamID              *string   `json:"team_id,omitempty" db:"team_id"`     // Set for a type offered by the whole team
+	Name                string    `json:"name" db:"name"`
+	DurationMinutes     int       `json:"duration_minutes" db:"duration_minutes"`
+	BufferBeforeMinutes int       `json:"buffer_before_minutes" db:"buffer_before_minutes"` // Free time kept before each appointment
+	BufferAfterMinutes  int       `json:"buffer_after_minutes" db:"buffer_after_minutes"`   // Free time kept after each appointment
+	Color               *string   `json:"color,omitempty" db:"color"`                       // Hex color shown on the calendar
+	ScheduleID          *string   `json:"schedule_id,omitempty" db:"schedule_id"`           // Availability schedule to book against (NULL = default schedule)
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAppointmentTypeRequest represents the request payload for creating an appointment type.
+// Setting team_id creates a type offered by every member of the team instead of a single provider.
+type CreateAppointmentTypeRequest struct {
+	ProviderID          *string `json:"provider_id"` // Admins only; defaults to the current user
+	TeamID              *string `json:"team_id"`     // Team admins only; team types always use each member's default schedule
+	Name                string  `json:"name" binding:"required"`
+	DurationMinutes     int     `json:"duration_minutes" binding:"omitempty,min=1,max=1440"`
+	BufferBeforeMinutes int     `json:"buffer_before_minutes" binding:"min=0,max=1440"`
+	BufferAfterMinutes  int     `json:"buffer_after_minutes" binding:"min=0,max=1440"`
+	Color               *string `json:"color" binding:"omitempty,hexcolor"`
+	ScheduleID          *string `json:"schedule_id"`
+}
+
+// UpdateAppointmentTypeRequest represents the request payload for updating an appointment type.
+// An empty schedule_id unassigns the schedule so the provider's default is used; an empty color clears it.
+type UpdateAppointmentTypeRequest struct {
+	Name                *string `json:"name"`
+	DurationMinutes     *int    `json:"duration_minutes" binding:"omitempty,min=1,max=1440"`
+	BufferBeforeMinutes *int    `json:"buffer_before_minutes" binding:"omitempty,min=0,max=1440"`
+	BufferAfterMinutes  *int    `json:"buffer_after_minutes" binding:"omitempty,min=0,max=1440"`
+	Color               *string `json:"color" binding:"omitempty,hexcolor|len=0"`
+	ScheduleID          *string `json:"schedule_id"`
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// uuidFilters are the GetAuditLog filters on UUID columns
+var uuidFilters = map[string]bool{"actor_id": true, "owner_id": true, "entity_id": true}
+
+type AuditHandler struct {
+	db *sql.DB
+}
+
+func NewAuditHandler(db *sql.DB) *AuditHandler {
+	return &AuditHandler{
+		db: db,
+	}
+}
+
+// GetAuditLog lists audit entries for providers within the admin's teams, and profile changes
+// of their patients, newest first.
+// Optional filters: actor_id, owner_id, entity_type, entity_id, action, start_date and end_date.
+func (ah *AuditHandler) GetAuditLog(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view the audit log"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200 // Max limit
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// Admins only see changes to data owned by providers in their teams. Patient profiles are
+	// owned by the patient, so those are scoped by the providers the patient has appointments with.
+	scope, args := userCtx.ProviderScope("owner_id", 1)
+	patientScope, patientArgs := userCtx.ProviderScope("e.created_by", len(args)+2)
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, actor_role, action, entity_type, entity_id, owner_id, changes, ip_address, created_at
+		FROM audit_log
+		WHERE (%s OR (entity_type = $%d AND owner_id IN (
+			SELECT e.patient_id FROM events e WHERE e.patient_id IS NOT NULL AND %s
+		)))`, scope, len(args)+1, patientScope)
+	args = append(append(args, auditlog.EntityUser), patientArgs...)
+	argIndex := 1 + len(args)
+
+	// Exact-match filters; the ID columns are UUIDs, so malformed IDs are rejected up front
+	for _, filter := range []string{"actor_id", "owner_id", "entity_type", "entity_id", "action"} {
+		if value := c.Query(filter); value != "" {
+			if uuidFilters[filter] {
+				if _, err := uuid.Parse(value); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s, must be a UUID", filter)})
+					return
+				}
+			}
+			query += fmt.Sprintf(" AND %s = $%d", filter, argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	// Time range filtering (RFC 3339 or YYYY-MM-DD; end_date dates are inclusive)
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := parseBound(startDate, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, use RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
+		args = append(args, start)
+		argIndex++
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := parseBound(endDate, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, use RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+		query += fmt.Sprintf(" AND created_at < $%d", argIndex)
+		args = append(args, end)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := ah.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log", "details": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []auditlog.Entry{}
+	for rows.Next() {
+		var entry auditlog.Entry
+		var changes []byte
+		err := rows.Scan(
+			&entry.ID, &entry.ActorID, &entry.ActorRole, &entry.Action, &entry.EntityType,
+			&entry.EntityID, &entry.OwnerID, &changes, &entry.IPAddress, &entry.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to scan audit entry: %v", err)})
+			return
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to decode audit entry: %v", err)})
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(entries),
+		},
+	})
+}
+
+// parseBound parses a time filter. A bare date used as an upper bound covers the whole day.
+func parseBound(value string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		return date.AddDate(0, 0, 1), nil
+	}
+	return date, nil
+}
@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"emr-calendar-backend/lib/auditlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditActor identifies the caller of a request in the audit log
+func (uc *UserContext) AuditActor(c *gin.Context) auditlog.Actor {
+	return auditlog.Actor{
+		UserID: uc.UserID,
+		Role:   uc.UserRole,
+		IP:     c.ClientIP(),
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 
+	"emr-calendar-backend/lib/auditlog"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -247,18 +249,38 @@ func (uh *UserHandler) CreateUserProfile(c *gin.Context) {
 	// Create user profile
 	profile := &UserProfile{
 		ID:          userContext.UserID,
+		Email:       userContext.Email,
 		FullName:    req.FullName,
 		Role:        req.Role,
 		Timezone:    req.Timezone,
 		PhoneNumber: req.PhoneNumber,
 	}
 
-	err := uh.createUserProfile(profile)
+	// Start transaction so the profile and its audit entry are recorded together
+	tx, err := uh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	err = uh.createUserProfile(tx, profile)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user profile"})
 		return
 	}
 
+	if err = auditlog.Record(tx, userContext.AuditActor(c), auditlog.ActionCreate, auditlog.EntityUser, profile.ID, profile.ID, nil, profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User profile created successfully",
 		"profile": profile,
@@ -268,13 +290,14 @@ func (uh *UserHandler) CreateUserProfile(c *gin.Context) {
 // Helper function to get user profile from database
 func (uh *UserHandler) getUserProfile(userID string) (*UserProfile, error) {
 	query := `
-		SELECT id, full_name, role, timezone, phone_number, created_at, updated_at
+		SELECT id, email, full_name, role, timezone, phone_number, created_at, updated_at
 		FROM users
 		WHERE id = $1`
 
 	profile := &UserProfile{}
 	err := uh.db.QueryRow(query, userID).Scan(
 		&profile.ID,
+		&profile.Email,
 		&profile.FullName,
 		&profile.Role,
 		&profile.Timezone,
@@ -291,14 +314,15 @@ func (uh *UserHandler) getUserProfile(userID string) (*UserProfile, error) {
 }
 
 // Helper function to create user profile
-func (uh *UserHandler) createUserProfile(profile *UserProfile) error {
+func (uh *UserHandler) createUserProfile(tx *sql.Tx, profile *UserProfile) error {
 	query := `
-		INSERT INTO users (id, full_name, role, timezone, phone_number, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+		INSERT INTO users (id, email, full_name, role, timezone, phone_number, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())`
 
-	_, err := uh.db.Exec(
+	_, err := tx.Exec(
 		query,
 		profile.ID,
+		profile.Email,
 		profile.FullName,
 		profile.Role,
 		profile.Timezone,
@@ -378,24 +402,3 @@ func (uh *UserHandler) GetUsersByRole(c *gin.Context) {
 		"users": users,
 	})
 }
-
-// Dashboard handlers for role-specific endpoints
-func ProviderDashboard(c *gin.Context) {
-	userCtx, _ := GetUserContext(c)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Provider dashboard access granted",
-		"user_id": userCtx.UserID,
-		"email":   userCtx.Email,
-		"role":    userCtx.UserRole,
-	})
-}
-
-func PatientDashboard(c *gin.Context) {
-	userCtx, _ := GetUserContext(c)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Patient dashboard access granted",
-		"user_id": userCtx.UserID,
-		"email":   userCtx.Email,
-		"role":    userCtx.UserRole,
-	})
-}
\ No newline at end of file
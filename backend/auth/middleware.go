@@ -70,6 +70,15 @@ func SupabaseAuthMiddlewareWithDB(jwtSecret string, db *sql.DB) gin.HandlerFunc
 			// If user not found in DB, don't fail - let the handler decide
 		}
 
+		// Load the user's team memberships to scope tenant data
+		if db != nil {
+			teamIDs, err := loadTeamIDs(db, userContext.UserID)
+			if err != nil {
+				fmt.Printf("Failed to fetch teams for user ID %s: %v\n", userContext.UserID, err)
+			}
+			userContext.TeamIDs = teamIDs
+		}
+
 		c.Set("user", userContext)
 		c.Next()
 	}
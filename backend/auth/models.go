@@ -34,14 +34,16 @@ type SupabaseClaims struct {
 
 // UserContext represents user information stored in request context
 type UserContext struct {
-	UserID   string // Supabase user ID
-	Email    string // User email
-	UserRole string // provider, patient, admin
+	UserID   string   // Supabase user ID
+	Email    string   // User email
+	UserRole string   // provider, patient, admin
+	TeamIDs  []string // Teams the user belongs to (tenant scope)
 }
 
 // UserProfile represents the profile data we store in our custom table
 type UserProfile struct {
 	ID          string    `json:"id" db:"id"`             // References auth.users(id)
+	Email       string    `json:"email" db:"email"`
 	FullName    string    `json:"full_name" db:"full_name"`
 	Role        string    `json:"role" db:"role"`         // provider, patient, admin
 	Timezone    string    `json:"timezone" db:"timezone"`
@@ -59,29 +61,73 @@ type Claims struct {
 
 // Event represents a calendar event in the system
 type Event struct {
-	ID          string    `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description *string   `json:"description,omitempty" db:"description"`
-	StartTime   time.Time `json:"start_time" db:"start_time"`
-	EndTime     time.Time `json:"end_time" db:"end_time"`
-	EventType   string    `json:"event_type" db:"event_type"` // "appointment" or "block"
-	Status      string    `json:"status" db:"status"`         // "pending", "confirmed", "cancelled"
-	CreatedBy   string    `json:"created_by" db:"created_by"` // Provider ID
-	PatientID   *string   `json:"patient_id,omitempty" db:"patient_id"` // Only for appointments
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID                 string     `json:"id" db:"id"`
+	Title              string     `json:"title" db:"title"`
+	Description        *string    `json:"description,omitempty" db:"description"`
+	StartTime          time.Time  `json:"start_time" db:"start_time"`
+	EndTime            time.Time  `json:"end_time" db:"end_time"`
+	EventType          string     `json:"event_type" db:"event_type"`                             // "appointment" or "block"
+	Status             string     `json:"status" db:"status"`                                     // "pending", "confirmed", "checked_in", "completed", "no_show", "cancelled"
+	CreatedBy          string     `json:"created_by" db:"created_by"`                             // Provider ID
+	PatientID          *string    `json:"patient_id,omitempty" db:"patient_id"`                   // Only for appointments
+	AppointmentTypeID  *string    `json:"appointment_type_id,omitempty" db:"appointment_type_id"` // Type the appointment was booked as
+	SeriesID           *string    `json:"series_id,omitempty" db:"series_id"`                     // Set for occurrences of a recurring series
+	IsException        bool       `json:"is_exception" db:"is_exception"`                         // Occurrence edited independently of its series
+	RescheduleCount    int        `json:"reschedule_count" db:"reschedule_count"`                 // Times the appointment has been moved
+	LateCancellation   bool       `json:"late_cancellation" db:"late_cancellation"`               // Cancelled within the policy's late-cancel window
+	CancellationReason *string    `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	CancelledBy        *string    `json:"cancelled_by,omitempty" db:"cancelled_by"` // User who cancelled the appointment
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	ProposedStartTime  *time.Time `json:"proposed_start_time,omitempty" db:"proposed_start_time"` // New time the provider proposed for a pending request
+	ProposedEndTime    *time.Time `json:"proposed_end_time,omitempty" db:"proposed_end_time"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // Set when soft deleted; deleted events are hidden from reads
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// EventSeries represents a recurring series whose occurrences are stored as events
+type EventSeries struct {
+	ID                string     `json:"id" db:"id"`
+	RRule             string     `json:"rrule" db:"rrule"`           // RFC 5545 RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO"
+	StartTime         time.Time  `json:"start_time" db:"start_time"` // Start of the first occurrence
+	EndTime           time.Time  `json:"end_time" db:"end_time"`     // End of the first occurrence
+	Until             *time.Time `json:"until,omitempty" db:"until"`
+	Count             *int       `json:"count,omitempty" db:"count"`
+	MaterializedUntil time.Time  `json:"materialized_until" db:"materialized_until"` // Start of the last occurrence stored so far
+	CreatedBy         string     `json:"created_by" db:"created_by"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// RecurrenceRequest describes how an event repeats; Until and Count override the RRULE's own bounds
+type RecurrenceRequest struct {
+	RRule string     `json:"rrule" binding:"required"`
+	Until *time.Time `json:"until"`
+	Count *int       `json:"count" binding:"omitempty,min=1"`
 }
 
 // CreateEventRequest represents the request payload for creating an event
 type CreateEventRequest struct {
-	Title       string    `json:"title" binding:"required"`
-	Description *string   `json:"description"`
-	StartTime   time.Time `json:"start_time" binding:"required"`
-	EndTime     time.Time `json:"end_time" binding:"required"`
-	EventType   string    `json:"event_type" binding:"required,oneof=appointment block"`
-	Status      string    `json:"status" binding:"omitempty,oneof=pending confirmed cancelled"`
-	PatientID   *string   `json:"patient_id"`
-	ProviderID  *string   `json:"provider_id"` // For admin use - specifies which provider should be the creator
+	Title             string             `json:"title"` // Required unless appointment_type_id is given
+	Description       *string            `json:"description"`
+	StartTime         time.Time          `json:"start_time" binding:"required"`
+	EndTime           time.Time          `json:"end_time"` // Required unless appointment_type_id is given
+	EventType         string             `json:"event_type" binding:"required,oneof=appointment block"`
+	Status            string             `json:"status" binding:"omitempty,oneof=pending confirmed"`
+	PatientID         *string            `json:"patient_id"`
+	ProviderID        *string            `json:"provider_id"`         // For admin use - specifies which provider should be the creator
+	AppointmentTypeID *string            `json:"appointment_type_id"` // Optional - sets the default title and duration, buffers and schedule
+	Recurrence        *RecurrenceRequest `json:"recurrence"`          // Optional - creates a recurring series instead of a single event
+	HoldID            *string            `json:"hold_id"`             // Optional - the caller's hold on the slot (POST /slots/hold), consumed by the booking
+}
+
+// BookAppointmentRequest represents the request payload for a patient booking one of a provider's slots
+type BookAppointmentRequest struct {
+	ProviderID        string    `json:"provider_id" binding:"required"`
+	StartTime         time.Time `json:"start_time" binding:"required"`
+	AppointmentTypeID string    `json:"appointment_type_id" binding:"required"` // Sets the length, buffers and schedule of the slot
+	Description       *string   `json:"description"`
+	HoldID            *string   `json:"hold_id"` // The caller's hold on the slot (POST /slots/hold), consumed by the booking
 }
 
 // UpdateEventRequest represents the request payload for updating an event
@@ -91,6 +137,22 @@ type UpdateEventRequest struct {
 	StartTime   *time.Time `json:"start_time"`
 	EndTime     *time.Time `json:"end_time"`
 	EventType   *string    `json:"event_type" binding:"omitempty,oneof=appointment block"`
-	Status      *string    `json:"status" binding:"omitempty,oneof=pending confirmed cancelled"`
+	Status      *string    `json:"status" binding:"omitempty,oneof=pending confirmed checked_in completed no_show cancelled"` // Must be an allowed transition
 	PatientID   *string    `json:"patient_id"`
-}
\ No newline at end of file
+}
+
+// CancelEventRequest represents the request payload for cancelling an appointment
+type CancelEventRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// DeclineAppointmentRequest represents the request payload for a provider declining a pending request
+type DeclineAppointmentRequest struct {
+	Reason *string `json:"reason"` // Passed on to the patient
+}
+
+// ProposeTimeRequest represents the request payload for a provider proposing another time for a pending request
+type ProposeTimeRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"` // The appointment keeps its length
+	Message   *string   `json:"message"`                       // Passed on to the patient
+}
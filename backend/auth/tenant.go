@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// loadTeamIDs returns the IDs of every team the user is a member of
+func loadTeamIDs(db *sql.DB, userID string) ([]string, error) {
+	rows, err := db.Query("SELECT team_id FROM providers WHERE user_id = $1", userID)
+	if err != nil {
+		return []string{}, err
+	}
+	defer rows.Close()
+
+	teamIDs := []string{}
+	for rows.Next() {
+		var teamID string
+		if err := rows.Scan(&teamID); err != nil {
+			return []string{}, err
+		}
+		teamIDs = append(teamIDs, teamID)
+	}
+
+	return teamIDs, rows.Err()
+}
+
+// IsStaff reports whether the user is a provider or admin, i.e. subject to tenant isolation
+func (uc *UserContext) IsStaff() bool {
+	return uc.UserRole == "provider" || uc.UserRole == "admin"
+}
+
+// ProviderScope returns a SQL predicate restricting column (a provider's user ID) to the
+// caller's tenant: the caller themselves or any provider sharing one of their teams.
+// argIndex is the next free placeholder index; the returned args must be appended in order.
+//
+// Patients do not belong to a team and may look up any provider's schedule, so the
+// predicate is always true for them. Their access to events is limited by patient_id instead.
+func (uc *UserContext) ProviderScope(column string, argIndex int) (string, []interface{}) {
+	if !uc.IsStaff() {
+		return "TRUE", nil
+	}
+
+	clause := fmt.Sprintf(
+		"(%s = $%d OR %s IN (SELECT user_id FROM providers WHERE team_id = ANY($%d)))",
+		column, argIndex, column, argIndex+1,
+	)
+	return clause, []interface{}{uc.UserID, pq.Array(uc.teamIDs())}
+}
+
+// CanAccessProvider reports whether the provider is within the caller's tenant
+func (uc *UserContext) CanAccessProvider(db *sql.DB, providerID string) (bool, error) {
+	if !uc.IsStaff() || providerID == uc.UserID {
+		return true, nil
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM providers
+			WHERE user_id = $1 AND team_id = ANY($2)
+		)`
+
+	var allowed bool
+	if err := db.QueryRow(query, providerID, pq.Array(uc.teamIDs())).Scan(&allowed); err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// teamIDs returns the tenant scope, never nil so it binds as an empty array rather than NULL
+func (uc *UserContext) teamIDs() []string {
+	if uc.TeamIDs == nil {
+		return []string{}
+	}
+	return uc.TeamIDs
+}
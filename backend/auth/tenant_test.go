@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"emr-calendar-backend/database"
+
+	"github.com/google/uuid"
+)
+
+// testDB connects to TEST_DATABASE_URL and applies the migrations. Tests that need
+// Postgres are skipped when it is not set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := database.Connect(databaseURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// createUser inserts a user that is removed, with everything it owns, when the test ends
+func createUser(t *testing.T, db *sql.DB, role string) string {
+	t.Helper()
+
+	id := uuid.New().String()
+	_, err := db.Exec(
+		`INSERT INTO users (id, email, full_name, role) VALUES ($1, $2, $3, $4)`,
+		id, id+"@example.test", "Test "+role, role,
+	)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, id) })
+	return id
+}
+
+// createTeam inserts a team with the given providers as members
+func createTeam(t *testing.T, db *sql.DB, providerIDs ...string) string {
+	t.Helper()
+
+	id := uuid.New().String()
+	if _, err := db.Exec(`INSERT INTO teams (id, name) VALUES ($1, $2)`, id, "Test team"); err != nil {
+		t.Fatalf("insert team: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM teams WHERE id = $1`, id) })
+
+	for _, providerID := range providerIDs {
+		if _, err := db.Exec(`INSERT INTO providers (team_id, user_id) VALUES ($1, $2)`, id, providerID); err != nil {
+			t.Fatalf("insert provider: %v", err)
+		}
+	}
+	return id
+}
+
+func TestProviderScope(t *testing.T) {
+	db := testDB(t)
+
+	caller := createUser(t, db, "provider")
+	teammate := createUser(t, db, "provider")
+	outsider := createUser(t, db, "provider")
+	teamID := createTeam(t, db, caller, teammate)
+	createTeam(t, db, outsider)
+
+	tests := []struct {
+		name    string
+		userCtx *UserContext
+		want    []string
+	}{
+		{
+			name:    "team member sees the team",
+			userCtx: &UserContext{UserID: caller, UserRole: "provider", TeamIDs: []string{teamID}},
+			want:    []string{caller, teammate},
+		},
+		{
+			name:    "no teams sees only themselves",
+			userCtx: &UserContext{UserID: caller, UserRole: "provider"},
+			want:    []string{caller},
+		},
+		{
+			name:    "patients are not scoped",
+			userCtx: &UserContext{UserID: uuid.New().String(), UserRole: "patient"},
+			want:    []string{caller, teammate, outsider},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, scopeArgs := tt.userCtx.ProviderScope("u.id", 4)
+			args := append([]interface{}{caller, teammate, outsider}, scopeArgs...)
+
+			rows, err := db.Query(`SELECT u.id FROM users u WHERE u.id IN ($1, $2, $3) AND `+scope, args...)
+			if err != nil {
+				t.Fatalf("scoped query: %v", err)
+			}
+			defer rows.Close()
+
+			got := map[string]bool{}
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					t.Fatalf("scan: %v", err)
+				}
+				got[id] = true
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("rows: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d providers, want %d", len(got), len(tt.want))
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("provider %s missing from scope", id)
+				}
+			}
+		})
+	}
+}
+
+func TestCanAccessProvider(t *testing.T) {
+	db := testDB(t)
+
+	caller := createUser(t, db, "provider")
+	teammate := createUser(t, db, "provider")
+	outsider := createUser(t, db, "provider")
+	teamID := createTeam(t, db, caller, teammate)
+	createTeam(t, db, outsider)
+
+	userCtx := &UserContext{UserID: caller, UserRole: "provider", TeamIDs: []string{teamID}}
+
+	tests := []struct {
+		name       string
+		providerID string
+		want       bool
+	}{
+		{"self", caller, true},
+		{"teammate", teammate, true},
+		{"other team", outsider, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := userCtx.CanAccessProvider(db, tt.providerID)
+			if err != nil {
+				t.Fatalf("CanAccessProvider: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CanAccessProvider(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -9,12 +9,18 @@ import (
 	"time"
 
 	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
 	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/timezone"
+	"emr-calendar-backend/lib/waitlist"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// maxOverrideRange bounds how long a single date-range override may be
+const maxOverrideRange = 366 * 24 * time.Hour
+
 type AvailabilityHandler struct {
 	db *sql.DB
 }
@@ -39,7 +45,7 @@ func (ah *AvailabilityHandler) GetAvailability(c *gin.Context) {
 
 	// Build query
 	query := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
+		SELECT id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
 		FROM availability
 		WHERE user_id = $1`
 	args := []interface{}{userCtx.UserID}
@@ -54,6 +60,13 @@ func (ah *AvailabilityHandler) GetAvailability(c *gin.Context) {
 		}
 	}
 
+	// Filter by schedule if provided
+	if scheduleID := c.Query("schedule_id"); scheduleID != "" {
+		query += fmt.Sprintf(" AND schedule_id = $%d", argIndex)
+		args = append(args, scheduleID)
+		argIndex++
+	}
+
 	// Filter by override vs recurring
 	if isOverride == "true" {
 		query += " AND override_date IS NOT NULL"
@@ -74,8 +87,8 @@ func (ah *AvailabilityHandler) GetAvailability(c *gin.Context) {
 	for rows.Next() {
 		var availability Availability
 		err := rows.Scan(
-			&availability.ID, &availability.UserID, &availability.DayOfWeek,
-			&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
+			&availability.ID, &availability.UserID, &availability.ScheduleID, &availability.DayOfWeek,
+			&availability.StartTime, &availability.EndTime, &availability.OverrideDate, &availability.OverrideEndDate,
 			&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
 		)
 		if err != nil {
@@ -117,24 +130,56 @@ func (ah *AvailabilityHandler) CreateAvailability(c *gin.Context) {
 		isAvailable = *req.IsAvailable
 	}
 
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Recurring rules belong to a schedule; overrides apply to all of them
+	var scheduleID *string
+	if req.DayOfWeek != nil {
+		if req.ScheduleID != nil {
+			owned, err := ah.ownsSchedule(tx, userCtx.UserID, *req.ScheduleID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+				return
+			}
+			if !owned {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+				return
+			}
+			scheduleID = req.ScheduleID
+		} else {
+			defaultID, err := ah.ensureDefaultSchedule(tx, userCtx.UserID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create default schedule", "details": err.Error()})
+				return
+			}
+			scheduleID = &defaultID
+		}
+	}
+
 	// Generate UUID for availability
 	availabilityID := uuid.New().String()
 
 	// Insert into database
 	query := `
-		INSERT INTO availability (id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at`
+		INSERT INTO availability (id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at`
 
 	var availability Availability
 	now := time.Now().UTC()
-	err := ah.db.QueryRow(
+	err = tx.QueryRow(
 		query,
-		availabilityID, userCtx.UserID, req.DayOfWeek, req.StartTime, req.EndTime,
+		availabilityID, userCtx.UserID, scheduleID, req.DayOfWeek, req.StartTime, req.EndTime,
 		req.OverrideDate, isAvailable, now, now,
 	).Scan(
-		&availability.ID, &availability.UserID, &availability.DayOfWeek,
-		&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
+		&availability.ID, &availability.UserID, &availability.ScheduleID, &availability.DayOfWeek,
+		&availability.StartTime, &availability.EndTime, &availability.OverrideDate, &availability.OverrideEndDate,
 		&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
 	)
 
@@ -143,6 +188,17 @@ func (ah *AvailabilityHandler) CreateAvailability(c *gin.Context) {
 		return
 	}
 
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionCreate, auditlog.EntityAvailability, availability.ID, availability.UserID, nil, availability); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"availability": availability})
 }
 
@@ -165,13 +221,13 @@ func (ah *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
 	// First, check if availability exists and belongs to user
 	var existingAvailability Availability
 	checkQuery := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
+		SELECT id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
 		FROM availability
 		WHERE id = $1 AND user_id = $2`
 
 	err := ah.db.QueryRow(checkQuery, availabilityID, userCtx.UserID).Scan(
-		&existingAvailability.ID, &existingAvailability.UserID, &existingAvailability.DayOfWeek,
-		&existingAvailability.StartTime, &existingAvailability.EndTime, &existingAvailability.OverrideDate,
+		&existingAvailability.ID, &existingAvailability.UserID, &existingAvailability.ScheduleID, &existingAvailability.DayOfWeek,
+		&existingAvailability.StartTime, &existingAvailability.EndTime, &existingAvailability.OverrideDate, &existingAvailability.OverrideEndDate,
 		&existingAvailability.IsAvailable, &existingAvailability.CreatedAt, &existingAvailability.UpdatedAt,
 	)
 
@@ -230,14 +286,22 @@ func (ah *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
 		UPDATE availability
 		SET %s
 		WHERE id = $%d AND user_id = $%d
-		RETURNING id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at`,
+		RETURNING id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at`,
 		strings.Join(updateFields, ", "),
 		argIndex, argIndex+1)
 
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
 	var updatedAvailability Availability
-	err = ah.db.QueryRow(updateQuery, args...).Scan(
-		&updatedAvailability.ID, &updatedAvailability.UserID, &updatedAvailability.DayOfWeek,
-		&updatedAvailability.StartTime, &updatedAvailability.EndTime, &updatedAvailability.OverrideDate,
+	err = tx.QueryRow(updateQuery, args...).Scan(
+		&updatedAvailability.ID, &updatedAvailability.UserID, &updatedAvailability.ScheduleID, &updatedAvailability.DayOfWeek,
+		&updatedAvailability.StartTime, &updatedAvailability.EndTime, &updatedAvailability.OverrideDate, &updatedAvailability.OverrideEndDate,
 		&updatedAvailability.IsAvailable, &updatedAvailability.CreatedAt, &updatedAvailability.UpdatedAt,
 	)
 
@@ -246,6 +310,17 @@ func (ah *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
 		return
 	}
 
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityAvailability, availabilityID, updatedAvailability.UserID, existingAvailability, updatedAvailability); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"availability": updatedAvailability})
 }
 
@@ -259,21 +334,41 @@ func (ah *AvailabilityHandler) DeleteAvailability(c *gin.Context) {
 
 	availabilityID := c.Param("id")
 
-	query := `DELETE FROM availability WHERE id = $1 AND user_id = $2`
-	result, err := ah.db.Exec(query, availabilityID, userCtx.UserID)
+	// Start transaction
+	tx, err := ah.db.Begin()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete availability"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	query := `
+		DELETE FROM availability WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at`
+
+	var deleted Availability
+	err = tx.QueryRow(query, availabilityID, userCtx.UserID).Scan(
+		&deleted.ID, &deleted.UserID, &deleted.ScheduleID, &deleted.DayOfWeek,
+		&deleted.StartTime, &deleted.EndTime, &deleted.OverrideDate, &deleted.OverrideEndDate,
+		&deleted.IsAvailable, &deleted.CreatedAt, &deleted.UpdatedAt,
+	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Availability rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete availability"})
 		return
 	}
 
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Availability rule not found"})
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionDelete, auditlog.EntityAvailability, availabilityID, deleted.UserID, deleted, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
@@ -294,20 +389,79 @@ func (ah *AvailabilityHandler) CreateOverride(c *gin.Context) {
 		return
 	}
 
-	// Validate that override date is in the future or today
-	// Note: OverrideDate already comes with timezone from frontend
-	// Just truncate to day boundary for comparison
-	today := time.Now().Truncate(24 * time.Hour)
-	overrideDate := req.OverrideDate.Truncate(24 * time.Hour)
-	if overrideDate.Before(today) {
+	// Resolve the override's date range; a single override_date is a one-day range
+	// Note: dates are calendar dates; their offsets from the frontend are ignored
+	var startDate, endDate time.Time
+	switch {
+	case req.OverrideDate != nil && req.StartDate == nil && req.EndDate == nil:
+		startDate = calendarDate(*req.OverrideDate)
+		endDate = startDate
+	case req.OverrideDate == nil && req.StartDate != nil:
+		startDate = calendarDate(*req.StartDate)
+		endDate = startDate
+		if req.EndDate != nil {
+			endDate = calendarDate(*req.EndDate)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify either override_date or start_date (with optional end_date)"})
+		return
+	}
+
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if endDate.Sub(startDate) > maxOverrideRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Override range cannot exceed one year"})
+		return
+	}
+
+	// Validate that override date is in the future or today, in the provider's timezone
+	loc, err := timezone.ForProvider(ah.db, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load provider timezone"})
+		return
+	}
+	today := timezone.Date(time.Now(), loc)
+	if startDate.Before(today) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Override date cannot be in the past"})
 		return
 	}
 
-	// Check if override already exists for this date
-	checkQuery := `SELECT id FROM availability WHERE user_id = $1 AND override_date = $2`
+	// Collect the override's windows; an available override without hours opens the whole day
+	windows := req.Windows
+	if len(windows) == 0 && req.StartTime != nil && req.EndTime != nil {
+		windows = []TimeRange{{StartTime: *req.StartTime, EndTime: *req.EndTime}}
+	}
+	if !req.IsAvailable {
+		windows = nil // A closed day has no hours
+	}
+	if err := validateWindows(windows); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar so two requests cannot both pass the overlap check
+	if err := conflicts.LockProvider(tx, userCtx.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	// Check if an override already covers any day of the range
+	checkQuery := `
+		SELECT id FROM availability
+		WHERE user_id = $1 AND override_date <= $3 AND COALESCE(override_end_date, override_date) >= $2
+		LIMIT 1`
 	var existingID string
-	err := ah.db.QueryRow(checkQuery, userCtx.UserID, req.OverrideDate).Scan(&existingID)
+	err = tx.QueryRow(checkQuery, userCtx.UserID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).Scan(&existingID)
 	if err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Override already exists for this date"})
 		return
@@ -316,33 +470,115 @@ func (ah *AvailabilityHandler) CreateOverride(c *gin.Context) {
 		return
 	}
 
-	// Generate UUID for override
-	overrideID := uuid.New().String()
+	// A multi-day range is stored as one record per window, not one per day
+	var overrideEndDate *string
+	if endDate.After(startDate) {
+		formatted := endDate.Format("2006-01-02")
+		overrideEndDate = &formatted
+	}
 
-	// Insert into database
+	// Insert into database, one row per window
 	query := `
-		INSERT INTO availability (id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
-		VALUES ($1, $2, NULL, $3, $4, $5, $6, $7, $8)
-		RETURNING id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at`
+		INSERT INTO availability (id, user_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at)
+		VALUES ($1, $2, NULL, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at`
 
-	var override Availability
+	rows := []TimeRange{{}}
+	if len(windows) > 0 {
+		rows = windows
+	}
+
+	overrides := []Availability{}
+	actor := userCtx.AuditActor(c)
 	now := time.Now().UTC()
-	err = ah.db.QueryRow(
-		query,
-		overrideID, userCtx.UserID, req.StartTime, req.EndTime,
-		req.OverrideDate, req.IsAvailable, now, now,
-	).Scan(
-		&override.ID, &override.UserID, &override.DayOfWeek,
-		&override.StartTime, &override.EndTime, &override.OverrideDate,
-		&override.IsAvailable, &override.CreatedAt, &override.UpdatedAt,
-	)
+	for _, window := range rows {
+		var startTime, endTime *string
+		if window.StartTime != "" {
+			startTime, endTime = &window.StartTime, &window.EndTime
+		}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create override", "details": err.Error()})
+		var override Availability
+		err = tx.QueryRow(
+			query,
+			uuid.New().String(), userCtx.UserID, startTime, endTime,
+			startDate.Format("2006-01-02"), overrideEndDate, req.IsAvailable, now, now,
+		).Scan(
+			&override.ID, &override.UserID, &override.ScheduleID, &override.DayOfWeek,
+			&override.StartTime, &override.EndTime, &override.OverrideDate, &override.OverrideEndDate,
+			&override.IsAvailable, &override.CreatedAt, &override.UpdatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create override", "details": err.Error()})
+			return
+		}
+		if err = auditlog.Record(tx, actor, auditlog.ActionCreate, auditlog.EntityAvailability, override.ID, override.UserID, nil, override); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+			return
+		}
+		overrides = append(overrides, override)
+	}
+
+	// Hours opened by the override are offered to the waitlist
+	if req.IsAvailable {
+		from, _ := timezone.StartOfDay(startDate, loc)
+		_, to := timezone.StartOfDay(endDate, loc)
+		if err := waitlist.OfferFreedTime(ah.db, tx, userCtx.UserID, from, to); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to offer freed time to the waitlist", "details": err.Error()})
+			return
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"override": override})
+	c.JSON(http.StatusCreated, gin.H{
+		"override":  overrides[0],
+		"overrides": overrides,
+	})
+}
+
+// calendarDate keeps only the calendar date of t, as midnight UTC
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// validateWindows checks that every window is "HH:MM" with end after start and that none overlap
+func validateWindows(windows []TimeRange) error {
+	type span struct{ start, end time.Time }
+	spans := make([]span, 0, len(windows))
+
+	for _, window := range windows {
+		// Accept both "HH:MM" and the "HH:MM:SS" form returned by the API
+		startTime, err := time.Parse("15:04", window.StartTime)
+		if err != nil {
+			if startTime, err = time.Parse("15:04:05", window.StartTime); err != nil {
+				return fmt.Errorf("invalid start_time format, use HH:MM")
+			}
+		}
+
+		endTime, err := time.Parse("15:04", window.EndTime)
+		if err != nil {
+			if endTime, err = time.Parse("15:04:05", window.EndTime); err != nil {
+				return fmt.Errorf("invalid end_time format, use HH:MM")
+			}
+		}
+
+		if !endTime.After(startTime) {
+			return fmt.Errorf("end_time must be after start_time")
+		}
+
+		for _, other := range spans {
+			if startTime.Before(other.end) && endTime.After(other.start) {
+				return fmt.Errorf("override windows must not overlap")
+			}
+		}
+		spans = append(spans, span{startTime, endTime})
+	}
+
+	return nil
 }
 
 // validateAvailabilityRequest validates the business logic for availability requests
@@ -407,6 +643,7 @@ func (ah *AvailabilityHandler) CheckConflicts(c *gin.Context) {
 
 	var req struct {
 		ProviderID string    `json:"provider_id" binding:"required"`
+		ScheduleID string    `json:"schedule_id"` // Optional, defaults to the provider's default schedule
 		StartTime  time.Time `json:"start_time" binding:"required"`
 		EndTime    time.Time `json:"end_time" binding:"required"`
 	}
@@ -416,199 +653,30 @@ func (ah *AvailabilityHandler) CheckConflicts(c *gin.Context) {
 		return
 	}
 
-	conflictChecker := conflicts.NewConflictChecker(ah.db)
-	result, err := conflictChecker.CheckTimeSlotAvailability(req.ProviderID, req.StartTime, req.EndTime)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check conflicts"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"conflict_check": result})
-}
-
-// GetSchedule retrieves the user's availability schedule in the frontend format
-func (ah *AvailabilityHandler) GetSchedule(c *gin.Context) {
-	userCtx, exists := auth.GetUserContext(c)
-	if !exists || userCtx == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
-		return
-	}
-	// Get all recurring availability rules for the user
-	query := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
-		FROM availability
-		WHERE user_id = $1 AND override_date IS NULL
-		ORDER BY day_of_week ASC`
-
-	rows, err := ah.db.Query(query, userCtx.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability", "details": err.Error()})
-		return
-	}
-	defer rows.Close()
-
-	var availabilities []Availability
-	for rows.Next() {
-		var availability Availability
-		err := rows.Scan(
-			&availability.ID, &availability.UserID, &availability.DayOfWeek,
-			&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
-			&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to scan availability: %v", err)})
-			return
-		}
-		availabilities = append(availabilities, availability)
-	}
-
-	// Convert to frontend format
-	schedule := convertToScheduleFormat(availabilities, userCtx.UserID)
-	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
-}
-
-// UpdateSchedule updates the complete availability schedule
-func (ah *AvailabilityHandler) UpdateSchedule(c *gin.Context) {
-	userCtx, exists := auth.GetUserContext(c)
-	if !exists || userCtx == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
-		return
-	}
-
-	var req UpdateScheduleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
-		return
-	}
-
-	// Start transaction
-	tx, err := ah.db.Begin()
+	// Staff may only check providers within their own teams
+	allowed, err := userCtx.CanAccessProvider(ah.db, req.ProviderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
-		return
-	}
-	defer tx.Rollback()
-
-	// If availability is being updated, replace all existing recurring rules
-	if req.Availability != nil {
-		// Delete all existing recurring availability rules
-		deleteQuery := `DELETE FROM availability WHERE user_id = $1 AND override_date IS NULL`
-		_, err = tx.Exec(deleteQuery, userCtx.UserID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing availability"})
-			return
-		}
-
-		// Insert new availability rules
-		for _, slot := range *req.Availability {
-			for _, day := range slot.Days {
-				availabilityID := uuid.New().String()
-
-				// Extract time from frontend format - keep in local time, don't convert to UTC
-				startTimeStr := slot.StartTime.Format("15:04:05")
-				endTimeStr := slot.EndTime.Format("15:04:05")
-
-				insertQuery := `
-					INSERT INTO availability (id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
-					VALUES ($1, $2, $3, $4, $5, NULL, true, $6, $7)`
-
-				now := time.Now().UTC()
-				_, err = tx.Exec(
-					insertQuery,
-					availabilityID, userCtx.UserID, day, startTimeStr, endTimeStr, now, now,
-				)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create availability rule", "details": err.Error()})
-					return
-				}
-			}
-		}
-	}
-
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
 		return
 	}
-
-	// Return updated schedule
-	ah.GetSchedule(c)
-}
-
-// CreateSchedule creates an initial availability schedule for new users
-func (ah *AvailabilityHandler) CreateSchedule(c *gin.Context) {
-	userCtx, exists := auth.GetUserContext(c)
-	if !exists || userCtx == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
 		return
 	}
 
-	var req Schedule
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
-		return
-	}
-
-	// Check if user already has availability records
-	checkQuery := `SELECT COUNT(*) FROM availability WHERE user_id = $1 AND override_date IS NULL`
-	var count int
-	err := ah.db.QueryRow(checkQuery, userCtx.UserID).Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing availability"})
-		return
-	}
-
-	if count > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "User already has availability schedule. Use PUT to update."})
-		return
-	}
+	conflictChecker := conflicts.NewConflictChecker(ah.db).WithScope(userCtx).WithSchedule(req.ScheduleID)
+	result, err := conflictChecker.CheckTimeSlotAvailability(req.ProviderID, req.StartTime, req.EndTime)
 
-	// Start transaction
-	tx, err := ah.db.Begin()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
-		return
-	}
-	defer tx.Rollback()
-
-	// Insert new availability rules
-	for _, slot := range req.Availability {
-		for _, day := range slot.Days {
-			availabilityID := uuid.New().String()
-
-			// Extract time from frontend format - keep in local time, don't convert to UTC
-			startTimeStr := slot.StartTime.Format("15:04:05")
-			endTimeStr := slot.EndTime.Format("15:04:05")
-
-			insertQuery := `
-				INSERT INTO availability (id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, NULL, true, $6, $7)`
-
-			now := time.Now().UTC()
-			_, err = tx.Exec(
-				insertQuery,
-				availabilityID, userCtx.UserID, day, startTimeStr, endTimeStr, now, now,
-			)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create availability rule", "details": err.Error()})
-				return
-			}
-		}
-	}
-
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check conflicts"})
 		return
 	}
 
-	// Return created schedule
-	ah.GetSchedule(c)
+	c.JSON(http.StatusOK, gin.H{"conflict_check": result})
 }
 
-// convertToScheduleFormat converts database availability records to frontend schedule format
-func convertToScheduleFormat(availabilities []Availability, userID string) Schedule {
+// convertToScheduleFormat converts a schedule's availability records to frontend schedule format
+func convertToScheduleFormat(schedule Schedule, availabilities []Availability) Schedule {
 	// Group availability rules by time slots
 	timeSlotMap := make(map[string][]int) // key: "startTime-endTime", value: array of days
 
@@ -648,11 +716,6 @@ func convertToScheduleFormat(availabilities []Availability, userID string) Sched
 		})
 	}
 
-	return Schedule{
-		ID:           1, // Fixed ID since we only have one schedule now
-		Name:         "Working Hours",
-		IsDefault:    true,
-		TimeZone:     "UTC",
-		Availability: slots,
-	}
+	schedule.Availability = slots
+	return schedule
 }
\ No newline at end of file
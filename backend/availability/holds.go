@@ -0,0 +1,187 @@
+package availability
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/holds"
+
+	"github.com/gin-gonic/gin"
+)
+
+// holdTTL is how long a slot stays held for checkout before anyone else can book it
+const holdTTL = 5 * time.Minute
+
+// HoldSlot reserves one of a provider's open slots for the caller while they complete the booking.
+// The slot must be listed by GetSlots with the same appointment type (or duration) and step; patients
+// hold the default slots of one of the provider's appointment types, as BookAppointment books them. It is
+// left out of other users' slot listings and rejected by their bookings until the hold is consumed
+// by creating the appointment with its hold_id, released, or expires after holdTTL. Holding a slot
+// releases the caller's previous checkout hold.
+func (ah *AvailabilityHandler) HoldSlot(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req HoldSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// Staff may only hold slots of providers within their own teams
+	allowed, err := userCtx.CanAccessProvider(ah.db, req.ProviderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
+		return
+	}
+
+	// Patients cannot choose the slot length or step themselves
+	if userCtx.UserRole == "patient" {
+		if req.AppointmentTypeID == nil || *req.AppointmentTypeID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "appointment_type_id is required"})
+			return
+		}
+		req.Step = 0
+	}
+
+	// An appointment type sets the slot length, buffers and schedule
+	duration := req.Duration
+	if duration == 0 {
+		duration = 30
+	}
+	var appointmentType *appointmenttypes.AppointmentType
+	if req.AppointmentTypeID != nil && *req.AppointmentTypeID != "" {
+		appointmentType, err = appointmenttypes.ForProvider(ah.db, *req.AppointmentTypeID, req.ProviderID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type is not offered by this provider"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
+		duration = appointmentType.DurationMinutes
+	}
+
+	startTime := req.StartTime.UTC()
+	endTime := startTime.Add(time.Duration(duration) * time.Minute)
+
+	// Only slots the provider publishes can be held
+	offered, err := OffersSlot(ah.db, req.ProviderID, appointmentType, startTime, endTime, req.Step, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return
+	}
+	if !offered {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Time slot not available",
+			"message": "Choose one of the provider's open slots from /api/v1/slots",
+		})
+		return
+	}
+
+	// Start transaction so the conflict check and the hold happen atomically
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := conflicts.LockProvider(tx, req.ProviderID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	// One checkout at a time; holds placed for waitlist offers are kept
+	_, err = tx.Exec(`
+		DELETE FROM slot_holds h
+		WHERE h.user_id = $1
+		AND NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.hold_id = h.id)`,
+		userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release previous hold"})
+		return
+	}
+
+	checker := conflicts.NewConflictChecker(ah.db).WithScope(userCtx).InTx(tx)
+	if appointmentType != nil {
+		checker.WithBuffers(appointmentType.BufferBeforeMinutes, appointmentType.BufferAfterMinutes)
+		if appointmentType.ScheduleID != nil {
+			checker.WithSchedule(*appointmentType.ScheduleID)
+		}
+	}
+	conflictResult, err := checker.CheckTimeSlotAvailability(req.ProviderID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return
+	}
+	if conflictResult.HasConflict {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                 "Time slot not available",
+			"conflict_type":         conflictResult.ConflictType,
+			"message":               conflictResult.Message,
+			"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+		})
+		return
+	}
+
+	hold, err := holds.Place(tx, req.ProviderID, userCtx.UserID, startTime, endTime, time.Now().UTC().Add(holdTTL))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hold slot"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"hold":        hold,
+		"ttl_seconds": int(holdTTL.Seconds()),
+	})
+}
+
+// ReleaseHold gives up one of the caller's checkout holds before it expires
+func (ah *AvailabilityHandler) ReleaseHold(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	result, err := ah.db.Exec(`
+		DELETE FROM slot_holds h
+		WHERE h.id = $1 AND h.user_id = $2
+		AND NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.hold_id = h.id)`,
+		c.Param("id"), userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release hold"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify release"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hold released successfully"})
+}
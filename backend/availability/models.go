@@ -5,20 +5,24 @@ import (
 )
 
 // Availability represents a provider's availability rule in the system
+
 type Availability struct {
-	ID           string     `json:"id" db:"id"`
-	UserID       string     `json:"user_id" db:"user_id"`
-	DayOfWeek    *int       `json:"day_of_week,omitempty" db:"day_of_week"`     // 0=Sunday, 6=Saturday (NULL for overrides)
-	StartTime    *string    `json:"start_time,omitempty" db:"start_time"`       // TIME format "09:00:00" (NULL for overrides)
-	EndTime      *string    `json:"end_time,omitempty" db:"end_time"`           // TIME format "17:00:00" (NULL for overrides)
-	OverrideDate *time.Time `json:"override_date,omitempty" db:"override_date"` // Specific date for override (NULL for recurring)
-	IsAvailable  bool       `json:"is_available" db:"is_available"`             // false for "closed" overrides
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	ID              string     `json:"id" db:"id"`
+	UserID          string     `json:"user_id" db:"user_id"`
+	ScheduleID      *string    `json:"schedule_id,omitempty" db:"schedule_id"`             // Schedule a recurring rule belongs to (NULL for overrides)
+	DayOfWeek       *int       `json:"day_of_week,omitempty" db:"day_of_week"`             // 0=Sunday, 6=Saturday (NULL for overrides)
+	StartTime       *string    `json:"start_time,omitempty" db:"start_time"`               // TIME format "09:00:00" (NULL for overrides)
+	EndTime         *string    `json:"end_time,omitempty" db:"end_time"`                   // TIME format "17:00:00" (NULL for overrides)
+	OverrideDate    *time.Time `json:"override_date,omitempty" db:"override_date"`         // Specific date for override (NULL for recurring)
+	OverrideEndDate *time.Time `json:"override_end_date,omitempty" db:"override_end_date"` // Last day of a date-range override (NULL = single day)
+	IsAvailable     bool       `json:"is_available" db:"is_available"`                     // false for "closed" overrides
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // CreateAvailabilityRequest represents the request payload for creating availability
 type CreateAvailabilityRequest struct {
+	ScheduleID   *string    `json:"schedule_id"` // Recurring rules only; defaults to the default schedule
 	DayOfWeek    *int       `json:"day_of_week" binding:"omitempty,min=0,max=6"`
 	StartTime    *string    `json:"start_time" binding:"omitempty"`
 	EndTime      *string    `json:"end_time" binding:"omitempty"`
@@ -34,12 +38,23 @@ type UpdateAvailabilityRequest struct {
 	IsAvailable *bool   `json:"is_available"`
 }
 
-// CreateOverrideRequest represents the request payload for creating date overrides
+// CreateOverrideRequest represents the request payload for creating date overrides.
+// Either a single OverrideDate or a StartDate/EndDate range (e.g. a vacation) is given.
+// Several windows (e.g. a split shift) can be given in Windows instead of StartTime/EndTime.
 type CreateOverrideRequest struct {
-	OverrideDate time.Time `json:"override_date" binding:"required"`
-	IsAvailable  bool      `json:"is_available"`
-	StartTime    *string   `json:"start_time" binding:"omitempty"`
-	EndTime      *string   `json:"end_time" binding:"omitempty"`
+	OverrideDate *time.Time  `json:"override_date"`
+	StartDate    *time.Time  `json:"start_date"`
+	EndDate      *time.Time  `json:"end_date"` // Inclusive; defaults to StartDate
+	IsAvailable  bool        `json:"is_available"`
+	StartTime    *string     `json:"start_time" binding:"omitempty"`
+	EndTime      *string     `json:"end_time" binding:"omitempty"`
+	Windows      []TimeRange `json:"windows" binding:"omitempty,dive"`
+}
+
+// TimeRange represents a wall-clock window within a day ("HH:MM")
+type TimeRange struct {
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
 }
 
 // TimeSlot represents an available time slot for booking
@@ -56,6 +71,39 @@ type SlotsResponse struct {
 	Total int        `json:"total_slots"`
 }
 
+// DaySlots represents the available slots on one day of a date range
+type DaySlots struct {
+	Date  string     `json:"date"`
+	Slots []TimeSlot `json:"slots"`
+	Total int        `json:"total_slots"`
+}
+
+// TeamSlot represents an open slot assigned to one of a team's providers
+type TeamSlot struct {
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Duration     int       `json:"duration_minutes"`
+	ProviderID   string    `json:"provider_id"`
+	ProviderName string    `json:"provider_name"`
+}
+
+// HoldSlotRequest represents the request payload for holding one of a provider's slots during checkout
+type HoldSlotRequest struct {
+	ProviderID        string    `json:"provider_id" binding:"required"`
+	StartTime         time.Time `json:"start_time" binding:"required"`
+	AppointmentTypeID *string   `json:"appointment_type_id"`                                 // Sets the length, buffers and schedule of the slot
+	Duration          int       `json:"duration_minutes" binding:"omitempty,min=1,max=1440"` // Slot length without an appointment type (default 30)
+	Step              int       `json:"step" binding:"omitempty,min=0"`                      // The step the slot was listed with, if any
+}
+
+// SlotsRangeResponse represents the response for available slots over a date range, grouped by day
+type SlotsRangeResponse struct {
+	StartDate string     `json:"start_date"`
+	EndDate   string     `json:"end_date"`
+	Days      []DaySlots `json:"days"`
+	Total     int        `json:"total_slots"`
+}
+
 
 // AvailabilitySlot represents a time slot for a specific set of days (frontend format)
 type AvailabilitySlot struct {
@@ -66,7 +114,7 @@ type AvailabilitySlot struct {
 
 // Schedule represents the complete schedule structure (frontend format)
 type Schedule struct {
-	ID           int                `json:"id"`
+	ID           string             `json:"id"`
 	Name         string             `json:"name" binding:"required"`
 	IsDefault    bool               `json:"isDefault"`
 	TimeZone     string             `json:"timeZone" binding:"required"`
@@ -0,0 +1,230 @@
+package availability
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/gin-gonic/gin"
+)
+
+// teamProvider is a team member whose calendar is searched for open slots
+type teamProvider struct {
+	ID   string
+	Name string
+}
+
+// GetNextSlots returns the earliest open slots across every provider of a team, from now up to
+// days ahead (default 14). Each start time is offered once and assigned to one provider: the
+// preferred provider_id when they are free, otherwise round-robin to whoever was least recently booked.
+// With appointment_type (or appointment_type_id), only providers offering the type are searched.
+func (ah *AvailabilityHandler) GetNextSlots(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	teamID := c.Query("team_id")
+	if teamID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "team_id parameter is required"})
+		return
+	}
+
+	// Staff may only search teams they belong to
+	if userCtx.IsStaff() && !isMember(userCtx, teamID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	// Parse count (default 5, max 50)
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count <= 0 {
+		count = 5
+	}
+	if count > 50 {
+		count = 50
+	}
+
+	// Parse days to search (default 14)
+	days, err := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if err != nil || days <= 0 {
+		days = 14
+	}
+	if days > maxSlotRangeDays {
+		days = maxSlotRangeDays
+	}
+
+	// Parse duration (default 30 minutes), ignored when an appointment type is given
+	duration, err := strconv.Atoi(c.DefaultQuery("duration", "30"))
+	if err != nil || duration <= 0 {
+		duration = 30
+	}
+
+	typeID := c.Query("appointment_type")
+	if typeID == "" {
+		typeID = c.Query("appointment_type_id")
+	}
+	preferredID := c.Query("provider_id")
+
+	providers, err := ah.getTeamProviders(teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team providers", "details": err.Error()})
+		return
+	}
+	if len(providers) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	now := time.Now().UTC()
+	candidates := []TeamSlot{}
+	searched := 0
+	for _, provider := range providers {
+		// Only providers offering the appointment type can be booked for it
+		var appointmentType *appointmenttypes.AppointmentType
+		if typeID != "" {
+			appointmentType, err = appointmenttypes.ForProvider(ah.db, typeID, provider.ID)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+				return
+			}
+		}
+		searched++
+
+		options, err := ah.resolveSlotOptions(provider.ID, appointmentType, duration, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load booking policy", "details": err.Error()})
+			return
+		}
+		options.holder = userCtx.UserID
+
+		scheduleID := ""
+		if appointmentType != nil && appointmentType.ScheduleID != nil {
+			scheduleID = *appointmentType.ScheduleID
+		}
+		schedule, err := conflicts.LoadSchedule(ah.db, provider.ID, scheduleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load schedule", "details": err.Error()})
+			return
+		}
+		if schedule == nil {
+			continue
+		}
+
+		// Search from the provider's local today
+		startDate := timezone.Date(now, schedule.Location)
+		daySlots, err := ah.generateSlotsForRange(provider.ID, schedule, startDate, startDate.AddDate(0, 0, days-1), options)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate slots", "details": err.Error()})
+			return
+		}
+
+		for _, day := range daySlots {
+			for _, slot := range day.Slots {
+				candidates = append(candidates, TeamSlot{
+					StartTime:    slot.StartTime,
+					EndTime:      slot.EndTime,
+					Duration:     slot.Duration,
+					ProviderID:   provider.ID,
+					ProviderName: provider.Name,
+				})
+			}
+		}
+	}
+
+	if typeID != "" && searched == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type is not offered by this team"})
+		return
+	}
+
+	slots := assignSlots(candidates, providers, preferredID, count)
+
+	c.JSON(http.StatusOK, gin.H{
+		"team_id": teamID,
+		"slots":   slots,
+		"count":   len(slots),
+	})
+}
+
+// assignSlots orders candidate slots by start time and keeps the first count distinct start
+// times, each assigned to the preferred provider if free, else the least recently booked one
+func assignSlots(candidates []TeamSlot, providers []teamProvider, preferredID string, count int) []TeamSlot {
+	rank := make(map[string]int, len(providers))
+	for i, provider := range providers {
+		rank[provider.ID] = i
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime)
+		}
+		if (a.ProviderID == preferredID) != (b.ProviderID == preferredID) {
+			return a.ProviderID == preferredID
+		}
+		return rank[a.ProviderID] < rank[b.ProviderID]
+	})
+
+	slots := []TeamSlot{}
+	for _, candidate := range candidates {
+		if len(slots) == count {
+			break
+		}
+		if len(slots) > 0 && slots[len(slots)-1].StartTime.Equal(candidate.StartTime) {
+			continue // Start time already assigned to a higher-ranked provider
+		}
+		slots = append(slots, candidate)
+	}
+	return slots
+}
+
+// getTeamProviders returns the team's members in round-robin order: least recently booked first,
+// then by when they joined the team
+func (ah *AvailabilityHandler) getTeamProviders(teamID string) ([]teamProvider, error) {
+	query := `
+		SELECT p.user_id, u.full_name
+		FROM providers p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN events e ON e.created_by = p.user_id AND e.event_type = 'appointment' AND e.deleted_at IS NULL
+		WHERE p.team_id = $1
+		GROUP BY p.user_id, u.full_name, p.created_at
+		ORDER BY MAX(e.created_at) ASC NULLS FIRST, p.created_at ASC`
+
+	rows, err := ah.db.Query(query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var providers []teamProvider
+	for rows.Next() {
+		var provider teamProvider
+		if err := rows.Scan(&provider.ID, &provider.Name); err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, rows.Err()
+}
+
+// isMember reports whether the team is within the user's tenant scope
+func isMember(userCtx *auth.UserContext, teamID string) bool {
+	for _, id := range userCtx.TeamIDs {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
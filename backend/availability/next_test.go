@@ -0,0 +1,75 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignSlots(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2026, 1, 5, hour, min, 0, 0, time.UTC) }
+	slot := func(providerID string, start time.Time) TeamSlot {
+		return TeamSlot{StartTime: start, EndTime: start.Add(30 * time.Minute), Duration: 30, ProviderID: providerID}
+	}
+
+	// Round-robin order: "b" was booked least recently
+	providers := []teamProvider{{ID: "b"}, {ID: "a"}, {ID: "c"}}
+
+	type assigned struct {
+		start      time.Time
+		providerID string
+	}
+
+	tests := []struct {
+		name        string
+		candidates  []TeamSlot
+		preferredID string
+		count       int
+		want        []assigned
+	}{
+		{
+			name:       "earliest start times first",
+			candidates: []TeamSlot{slot("a", at(10, 0)), slot("a", at(9, 0)), slot("c", at(9, 30))},
+			count:      5,
+			want:       []assigned{{at(9, 0), "a"}, {at(9, 30), "c"}, {at(10, 0), "a"}},
+		},
+		{
+			name:       "shared start time goes to the least recently booked",
+			candidates: []TeamSlot{slot("a", at(9, 0)), slot("c", at(9, 0)), slot("b", at(9, 0))},
+			count:      5,
+			want:       []assigned{{at(9, 0), "b"}},
+		},
+		{
+			name:        "preferred provider wins when free",
+			candidates:  []TeamSlot{slot("b", at(9, 0)), slot("c", at(9, 0)), slot("b", at(9, 30))},
+			preferredID: "c",
+			count:       5,
+			want:        []assigned{{at(9, 0), "c"}, {at(9, 30), "b"}},
+		},
+		{
+			name:       "count limits distinct start times",
+			candidates: []TeamSlot{slot("a", at(9, 0)), slot("b", at(9, 0)), slot("a", at(9, 30)), slot("a", at(10, 0))},
+			count:      2,
+			want:       []assigned{{at(9, 0), "b"}, {at(9, 30), "a"}},
+		},
+		{
+			name:       "no candidates",
+			candidates: nil,
+			count:      5,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := assignSlots(tt.candidates, providers, tt.preferredID, tt.count)
+			if len(slots) != len(tt.want) {
+				t.Fatalf("assignSlots() = %v, want %v", slots, tt.want)
+			}
+			for i, slot := range slots {
+				if !slot.StartTime.Equal(tt.want[i].start) || slot.ProviderID != tt.want[i].providerID {
+					t.Errorf("slot %d = %s at %v, want %s at %v", i, slot.ProviderID, slot.StartTime, tt.want[i].providerID, tt.want[i].start)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package availability
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultScheduleName is used for the schedule created implicitly for new providers
+const defaultScheduleName = "Working Hours"
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// GetSchedules lists all of the user's availability schedules, default first
+func (ah *AvailabilityHandler) GetSchedules(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	query := `
+		SELECT id, name, timezone, is_default
+		FROM schedules
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at ASC`
+
+	rows, err := ah.db.Query(query, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules", "details": err.Error()})
+		return
+	}
+
+	var schedules []Schedule
+	for rows.Next() {
+		var schedule Schedule
+		if err := rows.Scan(&schedule.ID, &schedule.Name, &schedule.TimeZone, &schedule.IsDefault); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to scan schedule: %v", err)})
+			return
+		}
+		schedules = append(schedules, schedule)
+	}
+	rows.Close()
+
+	for i := range schedules {
+		rules, err := ah.getScheduleRules(ah.db, schedules[i].ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability", "details": err.Error()})
+			return
+		}
+		schedules[i] = convertToScheduleFormat(schedules[i], rules)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// GetScheduleByID retrieves a single schedule in the frontend format
+func (ah *AvailabilityHandler) GetScheduleByID(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	schedule, err := ah.loadSchedule(ah.db, userCtx.UserID, c.Param("scheduleId"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// GetSchedule retrieves the user's default availability schedule in the frontend format
+func (ah *AvailabilityHandler) GetSchedule(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	schedule, err := ah.loadSchedule(ah.db, userCtx.UserID, "")
+	if err == sql.ErrNoRows {
+		// No schedule yet: return an empty default in the user's profile timezone
+		loc, err := timezone.ForProvider(ah.db, userCtx.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timezone", "details": err.Error()})
+			return
+		}
+		schedule = &Schedule{Name: defaultScheduleName, IsDefault: true, TimeZone: loc.String()}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// CreateSchedule creates a new named availability schedule.
+// The user's first schedule always becomes their default.
+func (ah *AvailabilityHandler) CreateSchedule(c *gin.Context) {
+	ah.createSchedule(c, false)
+}
+
+// CreateDefaultSchedule creates the user's default schedule. Unlike CreateSchedule it only
+// sets up a user without weekly hours; a user who already has them gets 409 and updates
+// them with PUT /availability/schedule instead.
+func (ah *AvailabilityHandler) CreateDefaultSchedule(c *gin.Context) {
+	ah.createSchedule(c, true)
+}
+
+// createSchedule creates a schedule from the request body; asDefault creates the default
+// schedule, failing if the user already has weekly hours or a default schedule
+func (ah *AvailabilityHandler) createSchedule(c *gin.Context, asDefault bool) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req Schedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if _, err := timezone.Load(req.TimeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM schedules WHERE user_id = $1`, userCtx.UserID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing schedules"})
+		return
+	}
+
+	if asDefault {
+		var hasSchedule bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM availability WHERE user_id = $1 AND override_date IS NULL)
+			OR EXISTS (SELECT 1 FROM schedules WHERE user_id = $1 AND is_default = true)`,
+			userCtx.UserID).Scan(&hasSchedule)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing availability"})
+			return
+		}
+		if hasSchedule {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already has availability schedule. Use PUT to update."})
+			return
+		}
+		req.IsDefault = true
+	}
+
+	scheduleID, err := insertSchedule(tx, userCtx.UserID, req.Name, req.TimeZone, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule", "details": err.Error()})
+		return
+	}
+
+	if count == 0 || req.IsDefault {
+		if err = setDefaultSchedule(tx, userCtx.UserID, scheduleID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default schedule", "details": err.Error()})
+			return
+		}
+	}
+
+	if err = replaceScheduleRules(tx, userCtx.UserID, scheduleID, req.Availability); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create availability rule", "details": err.Error()})
+		return
+	}
+
+	schedule, err := ah.loadSchedule(tx, userCtx.UserID, scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule", "details": err.Error()})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionCreate, auditlog.EntitySchedule, scheduleID, userCtx.UserID, nil, schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"schedule": schedule})
+}
+
+// UpdateSchedule updates the user's default schedule, creating it if needed
+func (ah *AvailabilityHandler) UpdateSchedule(c *gin.Context) {
+	ah.updateSchedule(c, "")
+}
+
+// UpdateScheduleByID renames a schedule, changes its timezone, makes it the default
+// and/or replaces its weekly availability
+func (ah *AvailabilityHandler) UpdateScheduleByID(c *gin.Context) {
+	ah.updateSchedule(c, c.Param("scheduleId"))
+}
+
+// updateSchedule applies an UpdateScheduleRequest to scheduleID (empty = default schedule)
+func (ah *AvailabilityHandler) updateSchedule(c *gin.Context, scheduleID string) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if req.TimeZone != nil {
+		if _, err := timezone.Load(*req.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var isDefault bool
+	if scheduleID == "" {
+		scheduleID, err = ah.ensureDefaultSchedule(tx, userCtx.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create default schedule", "details": err.Error()})
+			return
+		}
+		isDefault = true
+	} else {
+		err = tx.QueryRow(`SELECT is_default FROM schedules WHERE id = $1 AND user_id = $2`, scheduleID, userCtx.UserID).Scan(&isDefault)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+			return
+		}
+	}
+
+	// A provider always has a default; it changes by promoting another schedule
+	if req.IsDefault != nil && !*req.IsDefault && isDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set another schedule as default instead"})
+		return
+	}
+
+	before, err := ah.loadSchedule(tx, userCtx.UserID, scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule", "details": err.Error()})
+		return
+	}
+
+	if req.Name != nil || req.TimeZone != nil {
+		query := `
+			UPDATE schedules
+			SET name = COALESCE($1, name), timezone = COALESCE($2, timezone), updated_at = $3
+			WHERE id = $4`
+		if _, err = tx.Exec(query, req.Name, req.TimeZone, time.Now().UTC(), scheduleID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule", "details": err.Error()})
+			return
+		}
+	}
+
+	if req.IsDefault != nil && *req.IsDefault && !isDefault {
+		if err = setDefaultSchedule(tx, userCtx.UserID, scheduleID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default schedule", "details": err.Error()})
+			return
+		}
+	}
+
+	// If availability is being updated, replace all of the schedule's recurring rules
+	if req.Availability != nil {
+		if err = replaceScheduleRules(tx, userCtx.UserID, scheduleID, *req.Availability); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update availability", "details": err.Error()})
+			return
+		}
+	}
+
+	schedule, err := ah.loadSchedule(tx, userCtx.UserID, scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule", "details": err.Error()})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntitySchedule, scheduleID, userCtx.UserID, before, schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// DeleteSchedule deletes a schedule and its weekly rules.
+// Deleting the default schedule promotes the oldest remaining one.
+func (ah *AvailabilityHandler) DeleteSchedule(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	scheduleID := c.Param("scheduleId")
+
+	// Start transaction
+	tx, err := ah.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Snapshot the schedule with its rules for the audit log before they are deleted
+	before, err := ah.loadSchedule(tx, userCtx.UserID, scheduleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return
+	}
+
+	var wasDefault bool
+	err = tx.QueryRow(`DELETE FROM schedules WHERE id = $1 AND user_id = $2 RETURNING is_default`, scheduleID, userCtx.UserID).Scan(&wasDefault)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionDelete, auditlog.EntitySchedule, scheduleID, userCtx.UserID, before, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	if wasDefault {
+		query := `
+			UPDATE schedules SET is_default = true, updated_at = $2
+			WHERE id = (SELECT id FROM schedules WHERE user_id = $1 ORDER BY created_at ASC LIMIT 1)`
+		if _, err = tx.Exec(query, userCtx.UserID, time.Now().UTC()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote default schedule"})
+			return
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+}
+
+// loadSchedule loads a schedule with its weekly rules, returning sql.ErrNoRows if the user
+// does not own it. An empty scheduleID loads the user's default schedule.
+func (ah *AvailabilityHandler) loadSchedule(db execer, userID, scheduleID string) (*Schedule, error) {
+	query := `SELECT id, name, timezone, is_default FROM schedules WHERE user_id = $1 AND is_default = true`
+	args := []interface{}{userID}
+	if scheduleID != "" {
+		query = `SELECT id, name, timezone, is_default FROM schedules WHERE user_id = $1 AND id = $2`
+		args = append(args, scheduleID)
+	}
+
+	var schedule Schedule
+	err := db.QueryRow(query, args...).Scan(&schedule.ID, &schedule.Name, &schedule.TimeZone, &schedule.IsDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ah.getScheduleRules(db, schedule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule = convertToScheduleFormat(schedule, rules)
+	return &schedule, nil
+}
+
+// getScheduleRules gets the recurring availability rules of a schedule
+func (ah *AvailabilityHandler) getScheduleRules(db execer, scheduleID string) ([]Availability, error) {
+	query := `
+		SELECT id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
+		FROM availability
+		WHERE schedule_id = $1 AND override_date IS NULL
+		ORDER BY day_of_week ASC, start_time ASC`
+
+	rows, err := db.Query(query, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var availabilities []Availability
+	for rows.Next() {
+		var availability Availability
+		err := rows.Scan(
+			&availability.ID, &availability.UserID, &availability.ScheduleID, &availability.DayOfWeek,
+			&availability.StartTime, &availability.EndTime, &availability.OverrideDate, &availability.OverrideEndDate,
+			&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		availabilities = append(availabilities, availability)
+	}
+
+	return availabilities, rows.Err()
+}
+
+// ownsSchedule reports whether the schedule exists and belongs to the user
+func (ah *AvailabilityHandler) ownsSchedule(db execer, userID, scheduleID string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM schedules WHERE id = $1 AND user_id = $2)`, scheduleID, userID).Scan(&exists)
+	return exists, err
+}
+
+// ensureDefaultSchedule returns the user's default schedule ID, creating a default schedule
+// in the user's profile timezone if they do not have one yet
+func (ah *AvailabilityHandler) ensureDefaultSchedule(db execer, userID string) (string, error) {
+	var scheduleID string
+	err := db.QueryRow(`SELECT id FROM schedules WHERE user_id = $1 AND is_default = true`, userID).Scan(&scheduleID)
+	if err == nil {
+		return scheduleID, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	var timeZone string
+	err = db.QueryRow(`SELECT timezone FROM users WHERE id = $1`, userID).Scan(&timeZone)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if timeZone == "" {
+		timeZone = timezone.Default
+	}
+
+	return insertSchedule(db, userID, defaultScheduleName, timeZone, true)
+}
+
+// insertSchedule creates a schedule row and returns its ID
+func insertSchedule(db execer, userID, name, timeZone string, isDefault bool) (string, error) {
+	scheduleID := uuid.New().String()
+	now := time.Now().UTC()
+
+	query := `
+		INSERT INTO schedules (id, user_id, name, timezone, is_default, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := db.Exec(query, scheduleID, userID, name, timeZone, isDefault, now, now)
+	return scheduleID, err
+}
+
+// setDefaultSchedule makes scheduleID the user's only default schedule
+func setDefaultSchedule(tx *sql.Tx, userID, scheduleID string) error {
+	now := time.Now().UTC()
+	_, err := tx.Exec(`UPDATE schedules SET is_default = false, updated_at = $2 WHERE user_id = $1 AND is_default = true`, userID, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE schedules SET is_default = true, updated_at = $3 WHERE id = $1 AND user_id = $2`, scheduleID, userID, now)
+	return err
+}
+
+// replaceScheduleRules replaces every recurring rule of a schedule with the given slots
+func replaceScheduleRules(tx *sql.Tx, userID, scheduleID string, slots []AvailabilitySlot) error {
+	_, err := tx.Exec(`DELETE FROM availability WHERE schedule_id = $1 AND override_date IS NULL`, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO availability (id, user_id, schedule_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULL, true, $7, $8)`
+
+	for _, slot := range slots {
+		for _, day := range slot.Days {
+			// Extract time from frontend format - keep in local time, don't convert to UTC
+			startTimeStr := slot.StartTime.Format("15:04:05")
+			endTimeStr := slot.EndTime.Format("15:04:05")
+
+			now := time.Now().UTC()
+			_, err = tx.Exec(
+				insertQuery,
+				uuid.New().String(), userID, scheduleID, day, startTimeStr, endTimeStr, now, now,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
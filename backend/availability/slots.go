@@ -7,12 +7,39 @@ import (
 	"strconv"
 	"time"
 
+	"emr-calendar-backend/appointmenttypes"
 	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/policy"
+	"emr-calendar-backend/lib/timezone"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetSlots generates available time slots for a specific date
+// maxSlotRangeDays is the longest date range a single slot search may cover
+const maxSlotRangeDays = 62
+
+// slotOptions controls the length of generated slots, the free time kept around them and
+// around existing events, and how soon and how far ahead they can start
+type slotOptions struct {
+	duration          int       // Slot length in minutes
+	step              int       // Minutes between slot starts (0 = the slot length, restarting right after bookings)
+	bufferBefore      int       // Minutes that must be free before the slot
+	bufferAfter       int       // Minutes that must be free after the slot
+	eventBufferBefore int       // Minutes kept free before every existing event
+	eventBufferAfter  int       // Minutes kept free after every existing event
+	earliest          time.Time // Slots cannot start earlier (minimum notice)
+	latest            time.Time // Slots cannot start later (booking horizon; zero = no limit)
+	holder            string    // User whose own holds are not treated as booked (empty = none)
+}
+
+// GetSlots generates available time slots for a specific date, or for every date from start_date
+// to end_date (inclusive) grouped by day. With appointment_type_id, the
+// duration, buffers and schedule come from the appointment type. The provider's booking policy
+// sets the minimum notice, booking horizon and the buffers kept around every existing event.
+// Slots start every step (or interval) minutes; by default they follow each other back to back
+// and the next slot after a booking starts as soon as the booking ends. Slots held for other
+// users are not listed; the caller's own holds are.
 func (ah *AvailabilityHandler) GetSlots(c *gin.Context) {
 	userCtx, exists := auth.GetUserContext(c)
 	if !exists || userCtx == nil {
@@ -20,19 +47,40 @@ func (ah *AvailabilityHandler) GetSlots(c *gin.Context) {
 		return
 	}
 
-	// Parse query parameters
-	dateStr := c.Query("date")
-	if dateStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "date parameter is required (format: YYYY-MM-DD)"})
+	// Parse query parameters: a single date, or a start_date to end_date range
+	startDateStr, endDateStr := c.Query("start_date"), c.Query("end_date")
+	singleDay := startDateStr == "" && endDateStr == ""
+	if singleDay {
+		startDateStr = c.Query("date")
+		endDateStr = startDateStr
+		if startDateStr == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date (or start_date and end_date) parameter is required (format: YYYY-MM-DD)"})
+			return
+		}
+	} else if startDateStr == "" || endDateStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Both start_date and end_date are required for a date range"})
 		return
 	}
 
-	// Parse date
-	targetDate, err := time.Parse("2006-01-02", dateStr)
+	// Parse dates
+	startDate, err := time.Parse("2006-01-02", startDateStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", endDateStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
 		return
 	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if endDate.Sub(startDate) >= maxSlotRangeDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Date range cannot exceed %d days", maxSlotRangeDays)})
+		return
+	}
 
 	// Parse duration (default 30 minutes)
 	durationStr := c.DefaultQuery("duration", "30")
@@ -41,6 +89,16 @@ func (ah *AvailabilityHandler) GetSlots(c *gin.Context) {
 		duration = 30
 	}
 
+	// Parse step (default: slot length, snapping to the end of bookings)
+	stepStr := c.Query("step")
+	if stepStr == "" {
+		stepStr = c.Query("interval")
+	}
+	step, err := strconv.Atoi(stepStr)
+	if err != nil || step < 0 || userCtx.UserRole == "patient" {
+		step = 0 // Patients are shown the slots they can book
+	}
+
 	// Get provider ID (for now, using user_id since we don't have separate provider table)
 	providerID := c.Query("provider_id")
 	if providerID == "" {
@@ -48,114 +106,217 @@ func (ah *AvailabilityHandler) GetSlots(c *gin.Context) {
 		providerID = userCtx.UserID
 	}
 
-	// Generate slots
-	slots, err := ah.generateSlotsForDate(providerID, targetDate, duration)
+	// Staff may only view slots for providers within their own teams
+	allowed, err := userCtx.CanAccessProvider(ah.db, providerID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate slots", "details": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
 		return
 	}
 
-	response := SlotsResponse{
-		Date:  dateStr,
-		Slots: slots,
-		Total: len(slots),
+	// An appointment type sets the slot length, buffers and (optionally) the schedule
+	var appointmentType *appointmenttypes.AppointmentType
+	if typeID := c.Query("appointment_type_id"); typeID != "" {
+		appointmentType, err = appointmenttypes.ForProvider(ah.db, typeID, providerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type is not offered by this provider"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	options, err := ah.resolveSlotOptions(providerID, appointmentType, duration, step)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load booking policy", "details": err.Error()})
+		return
+	}
+	options.holder = userCtx.UserID
 
-// generateSlotsForDate generates available slots for a specific date and provider
-func (ah *AvailabilityHandler) generateSlotsForDate(providerID string, date time.Time, duration int) ([]TimeSlot, error) {
-	var slots []TimeSlot
+	// Weekly rules come from the requested schedule, or the provider's default one.
+	// The date and the availability hours are interpreted in the schedule's timezone.
+	scheduleID := c.Query("schedule_id")
+	if appointmentType != nil && appointmentType.ScheduleID != nil {
+		scheduleID = *appointmentType.ScheduleID
+	}
+	schedule, err := conflicts.LoadSchedule(ah.db, providerID, scheduleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load schedule", "details": err.Error()})
+		return
+	}
+	if schedule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
 
-	// Get availability for the date
-	availability, err := ah.getAvailabilityForDate(providerID, date)
+	// Generate slots
+	days, err := ah.generateSlotsForRange(providerID, schedule, startDate, endDate, options)
 	if err != nil {
-		return nil, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate slots", "details": err.Error()})
+		return
+	}
+
+	if singleDay {
+		c.JSON(http.StatusOK, SlotsResponse{
+			Date:  startDateStr,
+			Slots: days[0].Slots,
+			Total: days[0].Total,
+		})
+		return
 	}
 
-	// If no availability or not available, return empty slots
-	if availability == nil || !availability.IsAvailable {
-		return slots, nil
+	total := 0
+	for _, day := range days {
+		total += day.Total
 	}
 
-	// Get existing events for the date to exclude booked times
-	bookedSlots, err := ah.getBookedSlotsForDate(providerID, date)
-	if err != nil {
-		return nil, err
+	c.JSON(http.StatusOK, SlotsRangeResponse{
+		StartDate: startDateStr,
+		EndDate:   endDateStr,
+		Days:      days,
+		Total:     total,
+	})
+}
+
+// resolveSlotOptions builds the slot options for a provider: the slot length and buffers come from
+// the appointment type (when given) or duration, and the booking window and event buffers from the
+// provider's booking policy
+func (ah *AvailabilityHandler) resolveSlotOptions(providerID string, appointmentType *appointmenttypes.AppointmentType, duration, step int) (slotOptions, error) {
+	options := slotOptions{duration: duration, step: step}
+	if appointmentType != nil {
+		options.duration = appointmentType.DurationMinutes
+		options.bufferBefore = appointmentType.BufferBeforeMinutes
+		options.bufferAfter = appointmentType.BufferAfterMinutes
 	}
 
-	// Generate time slots based on availability
-	slots = ah.generateTimeSlots(date, availability, duration, bookedSlots)
+	bookingPolicy, err := policy.ForProvider(ah.db, providerID)
+	if err != nil {
+		return options, err
+	}
+	options.eventBufferBefore = bookingPolicy.BufferBeforeMinutes
+	options.eventBufferAfter = bookingPolicy.BufferAfterMinutes
+	options.earliest, options.latest = bookingPolicy.BookingWindow(time.Now().UTC())
 
-	return slots, nil
+	return options, nil
 }
 
-// getAvailabilityForDate gets the availability rule for a specific date
-func (ah *AvailabilityHandler) getAvailabilityForDate(providerID string, date time.Time) (*Availability, error) {
-	// First check for date-specific override
-	overrideQuery := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
-		FROM availability
-		WHERE user_id = $1 AND override_date = $2`
+// OffersSlot reports whether the slots engine offers the provider's slot from start to end, i.e. it
+// would be listed by GetSlots to holderID with the same appointment type (or duration) and step
+func OffersSlot(db *sql.DB, providerID string, appointmentType *appointmenttypes.AppointmentType, start, end time.Time, step int, holderID string) (bool, error) {
+	ah := NewAvailabilityHandler(db)
+	options, err := ah.resolveSlotOptions(providerID, appointmentType, int(end.Sub(start).Minutes()), step)
+	if err != nil {
+		return false, err
+	}
+	options.holder = holderID
 
-	var availability Availability
-	err := ah.db.QueryRow(overrideQuery, providerID, date).Scan(
-		&availability.ID, &availability.UserID, &availability.DayOfWeek,
-		&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
-		&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
-	)
+	scheduleID := ""
+	if appointmentType != nil && appointmentType.ScheduleID != nil {
+		scheduleID = *appointmentType.ScheduleID
+	}
+	schedule, err := conflicts.LoadSchedule(db, providerID, scheduleID)
+	if err != nil || schedule == nil {
+		return false, err
+	}
 
-	if err == nil {
-		return &availability, nil
+	date := timezone.Date(start, schedule.Location)
+	days, err := ah.generateSlotsForRange(providerID, schedule, date, date, options)
+	if err != nil {
+		return false, err
 	}
 
-	if err != sql.ErrNoRows {
-		return nil, err
+	for _, slot := range days[0].Slots {
+		if slot.StartTime.Equal(start) && slot.EndTime.Equal(end) {
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	// No override found, check recurring availability
-	dayOfWeek := int(date.Weekday())
-	recurringQuery := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
-		FROM availability
-		WHERE user_id = $1 AND day_of_week = $2 AND override_date IS NULL`
+// OpenSlots returns the provider's open slots of duration minutes on the schedule for every date
+// from startDate to endDate (inclusive), as GetSlots lists them without an appointment type
+func OpenSlots(db *sql.DB, providerID string, schedule *conflicts.ProviderSchedule, startDate, endDate time.Time, duration int) ([]DaySlots, error) {
+	ah := NewAvailabilityHandler(db)
+	options, err := ah.resolveSlotOptions(providerID, nil, duration, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ah.generateSlotsForRange(providerID, schedule, startDate, endDate, options)
+}
 
-	err = ah.db.QueryRow(recurringQuery, providerID, dayOfWeek).Scan(
-		&availability.ID, &availability.UserID, &availability.DayOfWeek,
-		&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
-		&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
-	)
+// generateSlotsForRange generates available slots for every calendar date from startDate to
+// endDate (inclusive) for a provider. Availability and existing events for the whole range are
+// each loaded in one batch; slots are returned in the schedule's timezone.
+func (ah *AvailabilityHandler) generateSlotsForRange(providerID string, schedule *conflicts.ProviderSchedule, startDate, endDate time.Time, options slotOptions) ([]DaySlots, error) {
+	loc := schedule.Location
 
-	if err == sql.ErrNoRows {
-		return nil, nil // No availability set for this day
+	// Get every available window per date (overrides replace the weekly rules)
+	windowsByDate, err := conflicts.NewConflictChecker(ah.db).RangeWindows(providerID, schedule, startDate, endDate)
+	if err != nil {
+		return nil, err
 	}
 
+	// Get existing events for the range to exclude booked times
+	rangeStart, _ := timezone.StartOfDay(startDate, loc)
+	_, rangeEnd := timezone.StartOfDay(endDate, loc)
+	bookedSlots, err := ah.getBookedSlots(providerID, rangeStart, rangeEnd, options)
 	if err != nil {
 		return nil, err
 	}
 
-	return &availability, nil
+	days := []DaySlots{}
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		var slots []TimeSlot
+		dateStr := date.Format("2006-01-02")
+
+		// Nothing can be booked on days beyond the booking horizon
+		startOfDay, _ := timezone.StartOfDay(date, loc)
+		if options.latest.IsZero() || !startOfDay.After(options.latest) {
+			// Generate time slots in each window (e.g. both halves of a split shift)
+			for _, window := range windowsByDate[dateStr] {
+				slots = append(slots, ah.generateTimeSlots(window, options, bookedSlots)...)
+			}
+		}
+
+		days = append(days, DaySlots{Date: dateStr, Slots: slots, Total: len(slots)})
+	}
+
+	return days, nil
 }
 
-// getBookedSlotsForDate gets all existing events for a specific date
-func (ah *AvailabilityHandler) getBookedSlotsForDate(providerID string, date time.Time) ([]TimeSlot, error) {
+// getBookedSlots gets all of the provider's existing events and unexpired holds overlapping [from, to),
+// except holds for options.holder. Each event is widened by the buffers of its appointment type, or the
+// event buffers in options when longer; holds by the event buffers.
+func (ah *AvailabilityHandler) getBookedSlots(providerID string, from, to time.Time, options slotOptions) ([]TimeSlot, error) {
 	var bookedSlots []TimeSlot
 
-	// Get events for the date
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
 	query := `
-		SELECT start_time, end_time
-		FROM events
-		WHERE created_by = $1
-		AND start_time >= $2
-		AND start_time < $3
-		AND status != 'cancelled'
-		ORDER BY start_time`
-
-	rows, err := ah.db.Query(query, providerID, startOfDay, endOfDay)
+		SELECT booked_start, booked_end
+		FROM (
+			SELECT e.start_time - make_interval(mins => GREATEST(COALESCE(at.buffer_before_minutes, 0), $4)) AS booked_start,
+			       e.end_time + make_interval(mins => GREATEST(COALESCE(at.buffer_after_minutes, 0), $5)) AS booked_end
+			FROM events e
+			LEFT JOIN appointment_types at ON at.id = e.appointment_type_id
+			WHERE e.created_by = $1
+			AND e.status != 'cancelled'
+			AND e.deleted_at IS NULL
+			UNION ALL
+			SELECT h.start_time - make_interval(mins => $4), h.end_time + make_interval(mins => $5)
+			FROM slot_holds h
+			WHERE h.provider_id = $1
+			AND h.expires_at > $6
+			AND h.user_id::text != $7
+		) booked
+		WHERE booked_start < $3
+		AND booked_end > $2
+		ORDER BY booked_start`
+
+	rows, err := ah.db.Query(query, providerID, from, to, options.eventBufferBefore, options.eventBufferAfter, time.Now().UTC(), options.holder)
 	if err != nil {
 		return nil, err
 	}
@@ -177,75 +338,60 @@ func (ah *AvailabilityHandler) getBookedSlotsForDate(providerID string, date tim
 	return bookedSlots, nil
 }
 
-// generateTimeSlots generates available time slots based on availability and booked times
-func (ah *AvailabilityHandler) generateTimeSlots(date time.Time, availability *Availability, duration int, bookedSlots []TimeSlot) []TimeSlot {
+// generateTimeSlots generates available time slots within an availability window, skipping
+// slots outside the booking window and slots that (with their buffers) overlap booked times.
+// Without a step, a slot that overlaps a booking is moved to start right after the booking.
+func (ah *AvailabilityHandler) generateTimeSlots(window conflicts.TimeWindow, options slotOptions, bookedSlots []TimeSlot) []TimeSlot {
 	var slots []TimeSlot
-
-	// Parse start and end times
-	if availability.StartTime == nil || availability.EndTime == nil {
-		return slots // No time range specified
-	}
-
-	startTimeStr := *availability.StartTime
-	endTimeStr := *availability.EndTime
-
-	// Parse time strings (format: "09:00:00" or "09:00")
-	startTime, err := parseTimeOfDay(startTimeStr)
-	if err != nil {
-		return slots
-	}
-
-	endTime, err := parseTimeOfDay(endTimeStr)
-	if err != nil {
-		return slots
-	}
-
-	// Create full datetime for the date
-	startDateTime := time.Date(date.Year(), date.Month(), date.Day(), startTime.Hour(), startTime.Minute(), 0, 0, time.UTC)
-	endDateTime := time.Date(date.Year(), date.Month(), date.Day(), endTime.Hour(), endTime.Minute(), 0, 0, time.UTC)
+	startDateTime, endDateTime := window.Start, window.End
 
 	// Generate slots in increments
-	slotDuration := time.Duration(duration) * time.Minute
+	slotDuration := time.Duration(options.duration) * time.Minute
+	bufferBefore := time.Duration(options.bufferBefore) * time.Minute
+	bufferAfter := time.Duration(options.bufferAfter) * time.Minute
+	step := time.Duration(options.step) * time.Minute
 	current := startDateTime
 
 	for current.Add(slotDuration).Before(endDateTime) || current.Add(slotDuration).Equal(endDateTime) {
 		slotEnd := current.Add(slotDuration)
 
 		// Check if this slot conflicts with any booked slot
-		if !ah.isSlotBooked(current, slotEnd, bookedSlots) {
+		bookedUntil, booked := ah.bookedUntil(current.Add(-bufferBefore), slotEnd.Add(bufferAfter), bookedSlots)
+		if booked && step == 0 {
+			// Snap the next candidate to the end of the booking (and its buffers)
+			current = bookedUntil.Add(bufferBefore)
+			continue
+		}
+
+		// Check the slot is within the booking window
+		bookable := !current.Before(options.earliest) && (options.latest.IsZero() || !current.After(options.latest))
+		if bookable && !booked {
 			slots = append(slots, TimeSlot{
 				StartTime: current,
 				EndTime:   slotEnd,
-				Duration:  duration,
+				Duration:  options.duration,
 			})
 		}
 
-		current = current.Add(slotDuration)
+		if step > 0 {
+			current = current.Add(step)
+		} else {
+			current = current.Add(slotDuration)
+		}
 	}
 
 	return slots
 }
 
-// parseTimeOfDay parses time string in format "HH:MM" or "HH:MM:SS"
-func parseTimeOfDay(timeStr string) (time.Time, error) {
-	// Try HH:MM:SS format first
-	if t, err := time.Parse("15:04:05", timeStr); err == nil {
-		return t, nil
-	}
-	// Try HH:MM format
-	if t, err := time.Parse("15:04", timeStr); err == nil {
-		return t, nil
-	}
-	return time.Time{}, fmt.Errorf("invalid time format: %s", timeStr)
-}
-
-// isSlotBooked checks if a potential slot conflicts with any booked slots
-func (ah *AvailabilityHandler) isSlotBooked(slotStart, slotEnd time.Time, bookedSlots []TimeSlot) bool {
+// bookedUntil checks if a potential slot conflicts with any booked slots, and returns the
+// time the last conflicting booking ends
+func (ah *AvailabilityHandler) bookedUntil(slotStart, slotEnd time.Time, bookedSlots []TimeSlot) (time.Time, bool) {
+	var until time.Time
 	for _, booked := range bookedSlots {
 		// Check for overlap: slot overlaps if it starts before booked ends and ends after booked starts
-		if slotStart.Before(booked.EndTime) && slotEnd.After(booked.StartTime) {
-			return true
+		if slotStart.Before(booked.EndTime) && slotEnd.After(booked.StartTime) && booked.EndTime.After(until) {
+			until = booked.EndTime
 		}
 	}
-	return false
+	return until, !until.IsZero()
 }
\ No newline at end of file
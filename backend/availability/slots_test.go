@@ -0,0 +1,94 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"emr-calendar-backend/lib/conflicts"
+)
+
+func TestGenerateTimeSlots(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2026, 1, 5, hour, min, 0, 0, time.UTC) }
+	booked := func(startHour, startMin, endHour, endMin int) TimeSlot {
+		return TimeSlot{StartTime: at(startHour, startMin), EndTime: at(endHour, endMin)}
+	}
+	window := conflicts.TimeWindow{Start: at(9, 0), End: at(11, 0)}
+
+	tests := []struct {
+		name    string
+		window  conflicts.TimeWindow
+		options slotOptions
+		booked  []TimeSlot
+		want    []time.Time // Slot start times
+	}{
+		{
+			name:    "back to back",
+			window:  window,
+			options: slotOptions{duration: 30},
+			want:    []time.Time{at(9, 0), at(9, 30), at(10, 0), at(10, 30)},
+		},
+		{
+			name:    "slot must fit before the window ends",
+			window:  conflicts.TimeWindow{Start: at(9, 0), End: at(10, 20)},
+			options: slotOptions{duration: 30},
+			want:    []time.Time{at(9, 0), at(9, 30)},
+		},
+		{
+			name:    "step shorter than the slot",
+			window:  window,
+			options: slotOptions{duration: 60, step: 15},
+			want:    []time.Time{at(9, 0), at(9, 15), at(9, 30), at(9, 45), at(10, 0)},
+		},
+		{
+			name:    "without a step the next slot snaps to the end of a booking",
+			window:  window,
+			options: slotOptions{duration: 30},
+			booked:  []TimeSlot{booked(9, 10, 9, 25)},
+			want:    []time.Time{at(9, 25), at(9, 55), at(10, 25)},
+		},
+		{
+			name:    "with a step slots stay on the grid",
+			window:  window,
+			options: slotOptions{duration: 30, step: 30},
+			booked:  []TimeSlot{booked(9, 10, 9, 25)},
+			want:    []time.Time{at(9, 30), at(10, 0), at(10, 30)},
+		},
+		{
+			name:    "buffers keep distance from bookings",
+			window:  window,
+			options: slotOptions{duration: 30, bufferBefore: 10, bufferAfter: 10},
+			booked:  []TimeSlot{booked(10, 0, 10, 15)},
+			want:    []time.Time{at(9, 0), at(10, 25)},
+		},
+		{
+			name:    "minimum notice",
+			window:  window,
+			options: slotOptions{duration: 30, earliest: at(9, 45)},
+			want:    []time.Time{at(10, 0), at(10, 30)},
+		},
+		{
+			name:    "booking horizon",
+			window:  window,
+			options: slotOptions{duration: 30, latest: at(9, 30)},
+			want:    []time.Time{at(9, 0), at(9, 30)},
+		},
+	}
+
+	ah := &AvailabilityHandler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := ah.generateTimeSlots(tt.window, tt.options, tt.booked)
+			if len(slots) != len(tt.want) {
+				t.Fatalf("generateTimeSlots() = %v, want starts %v", slots, tt.want)
+			}
+			for i, slot := range slots {
+				if !slot.StartTime.Equal(tt.want[i]) {
+					t.Errorf("slot %d starts at %v, want %v", i, slot.StartTime, tt.want[i])
+				}
+				if got := slot.EndTime.Sub(slot.StartTime); got != time.Duration(tt.options.duration)*time.Minute {
+					t.Errorf("slot %d lasts %v, want %d minutes", i, got, tt.options.duration)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -15,9 +16,11 @@ type Config struct {
 
 	// Database Configuration
 	DatabaseURL string
+	AutoMigrate bool // Apply pending migrations on startup
 
 	// Server Configuration
 	Port string
+	TrustedProxies []string // Proxies whose X-Forwarded-For header is believed; empty = none
 }
 
 func Load() (*Config, error) {
@@ -29,7 +32,9 @@ func Load() (*Config, error) {
 		SupabaseAnonKey:   getEnv("SUPABASE_ANON_KEY", ""),
 		SupabaseJWTSecret: getEnv("SUPABASE_JWT_SECRET", ""),
 		DatabaseURL:       getEnv("DATABASE_URL", ""),
+		AutoMigrate:       getEnv("AUTO_MIGRATE", "true") == "true",
 		Port:              getEnv("PORT", "5555"),
+		TrustedProxies:    parseList(getEnv("TRUSTED_PROXIES", "")),
 	}
 
 	return cfg, nil
@@ -42,6 +47,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// parseList splits a comma-separated value such as "10.0.0.1,10.0.1.0/24"
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func parseInt(value string, defaultValue int) int {
 	if intVal, err := strconv.Atoi(value); err == nil {
 		return intVal
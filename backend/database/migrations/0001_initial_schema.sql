@@ -1,11 +1,13 @@
--- EMR Calendar MVP Database Schema
--- 3 Tables: users, events, availability
+-- 0001: Initial schema
+-- 4 Tables: users, refresh_tokens, events, availability
+-- The schema as deployed before migrations were versioned (schema.sql and migrations.sql).
+-- Statements are idempotent so an existing database is adopted as version 1 unchanged.
 
 -- Extension for UUID generation
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- 1. Users Table (simplified - no teams/providers separation)
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     email VARCHAR(255) UNIQUE NOT NULL,
     full_name VARCHAR(255) NOT NULL,
@@ -16,8 +18,18 @@ CREATE TABLE users (
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
 
--- 2. Events Table (appointments + blocks unified)
-CREATE TABLE events (
+-- 2. Refresh Tokens Table (hashed refresh tokens issued by auth.TokenService)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- 3. Events Table (appointments + blocks unified)
+CREATE TABLE IF NOT EXISTS events (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     title VARCHAR(255) NOT NULL,
     description TEXT,
@@ -38,8 +50,8 @@ CREATE TABLE events (
     )
 );
 
--- 3. Availability Table (recurring rules + overrides)
-CREATE TABLE availability (
+-- 4. Availability Table (recurring rules + overrides)
+CREATE TABLE IF NOT EXISTS availability (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 
@@ -64,13 +76,16 @@ CREATE TABLE availability (
 );
 
 -- Indexes for performance
-CREATE INDEX idx_events_created_by ON events(created_by);
-CREATE INDEX idx_events_start_time ON events(start_time);
-CREATE INDEX idx_events_patient_id ON events(patient_id);
-CREATE INDEX idx_events_type ON events(event_type);
-CREATE INDEX idx_availability_user_id ON availability(user_id);
-CREATE INDEX idx_availability_day_of_week ON availability(day_of_week);
-CREATE INDEX idx_availability_override_date ON availability(override_date);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX IF NOT EXISTS idx_events_created_by ON events(created_by);
+CREATE INDEX IF NOT EXISTS idx_events_start_time ON events(start_time);
+CREATE INDEX IF NOT EXISTS idx_events_patient_id ON events(patient_id);
+CREATE INDEX IF NOT EXISTS idx_events_type ON events(event_type);
+CREATE INDEX IF NOT EXISTS idx_availability_user_id ON availability(user_id);
+CREATE INDEX IF NOT EXISTS idx_availability_day_of_week ON availability(day_of_week);
+CREATE INDEX IF NOT EXISTS idx_availability_override_date ON availability(override_date);
 
 -- Updated_at trigger function
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -82,24 +97,11 @@ END;
 $$ language 'plpgsql';
 
 -- Apply updated_at triggers
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_refresh_tokens_updated_at ON refresh_tokens;
+CREATE TRIGGER update_refresh_tokens_updated_at BEFORE UPDATE ON refresh_tokens FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_events_updated_at ON events;
 CREATE TRIGGER update_events_updated_at BEFORE UPDATE ON events FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_availability_updated_at ON availability;
 CREATE TRIGGER update_availability_updated_at BEFORE UPDATE ON availability FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
--- Sample data for testing
-INSERT INTO users (id, email, full_name, role, timezone) VALUES
-('ea21c216-064b-4972-922c-5f86705df4c4', 'test@example.com', 'Test Provider', 'provider', 'UTC'),
-('f1234567-1234-1234-1234-123456789012', 'patient@example.com', 'Test Patient', 'patient', 'UTC');
-
--- Sample recurring availability (Mon-Fri 9-5)
-INSERT INTO availability (user_id, day_of_week, start_time, end_time) VALUES
-('ea21c216-064b-4972-922c-5f86705df4c4', 1, '09:00', '17:00'), -- Monday
-('ea21c216-064b-4972-922c-5f86705df4c4', 2, '09:00', '17:00'), -- Tuesday
-('ea21c216-064b-4972-922c-5f86705df4c4', 3, '09:00', '17:00'), -- Wednesday
-('ea21c216-064b-4972-922c-5f86705df4c4', 4, '09:00', '17:00'), -- Thursday
-('ea21c216-064b-4972-922c-5f86705df4c4', 5, '09:00', '17:00'); -- Friday
-
--- Sample events
-INSERT INTO events (title, start_time, end_time, event_type, status, created_by, patient_id) VALUES
-('Doctor Appointment', '2025-09-17 10:00:00+00', '2025-09-17 11:00:00+00', 'appointment', 'confirmed', 'ea21c216-064b-4972-922c-5f86705df4c4', 'f1234567-1234-1234-1234-123456789012'),
-('Lunch Break', '2025-09-17 12:00:00+00', '2025-09-17 13:00:00+00', 'block', 'confirmed', 'ea21c216-064b-4972-922c-5f86705df4c4', NULL);
\ No newline at end of file
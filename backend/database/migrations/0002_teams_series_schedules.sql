@@ -0,0 +1,103 @@
+-- 0002: Teams, recurring series, named schedules and appointment types
+-- Builds on the 0001 baseline: adds the tables for teams, series, schedules and appointment
+-- types and the columns they need on the 0001 tables. Statements are idempotent, so databases
+-- that already had these tables before migrations were versioned are adopted unchanged.
+
+-- Teams (tenant organizations that group providers)
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    color VARCHAR(20) NOT NULL DEFAULT '#6366f1',
+    timezone VARCHAR(100) NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Providers (team membership)
+CREATE TABLE IF NOT EXISTS providers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    is_visible BOOLEAN NOT NULL DEFAULT true, -- Show this provider's calendar in the team view
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_team_member UNIQUE (team_id, user_id)
+);
+
+-- Event series (recurring events; occurrences are stored in events)
+CREATE TABLE IF NOT EXISTS event_series (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rrule TEXT NOT NULL, -- RFC 5545 RRULE, e.g. FREQ=WEEKLY;BYDAY=MO;COUNT=12
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL, -- First occurrence
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    until TIMESTAMP WITH TIME ZONE,
+    count INTEGER CHECK (count > 0),
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT series_end_after_start CHECK (end_time > start_time)
+);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES event_series(id) ON DELETE SET NULL; -- Only for recurring occurrences
+ALTER TABLE events ADD COLUMN IF NOT EXISTS is_exception BOOLEAN NOT NULL DEFAULT false; -- Occurrence moved independently of its series
+
+-- Schedules (named sets of weekly availability, e.g. "Clinic hours")
+CREATE TABLE IF NOT EXISTS schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    timezone VARCHAR(100) NOT NULL DEFAULT 'UTC', -- Zone the schedule's hours are defined in
+    is_default BOOLEAN NOT NULL DEFAULT false, -- Used when no schedule is requested explicitly
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Recurring rules belong to a schedule; overrides apply to every schedule
+ALTER TABLE availability ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES schedules(id) ON DELETE CASCADE;
+
+-- A date-range override runs from override_date to override_end_date, inclusive (NULL = single day)
+ALTER TABLE availability ADD COLUMN IF NOT EXISTS override_end_date DATE;
+
+-- NOT VALID: weekly rules created before schedules existed get one in 0011
+ALTER TABLE availability DROP CONSTRAINT IF EXISTS recurring_needs_schedule;
+ALTER TABLE availability ADD CONSTRAINT recurring_needs_schedule CHECK (day_of_week IS NULL OR schedule_id IS NOT NULL) NOT VALID;
+ALTER TABLE availability DROP CONSTRAINT IF EXISTS override_range;
+ALTER TABLE availability ADD CONSTRAINT override_range CHECK (override_end_date IS NULL OR (override_date IS NOT NULL AND override_end_date >= override_date));
+
+-- Appointment types (bookable services offered by a provider)
+CREATE TABLE IF NOT EXISTS appointment_types (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    duration_minutes INTEGER NOT NULL DEFAULT 30 CHECK (duration_minutes > 0),
+    schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL, -- NULL = provider's default schedule
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_events_series_id ON events(series_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_schedules_user_id ON schedules(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedules_one_default ON schedules(user_id) WHERE is_default;
+CREATE INDEX IF NOT EXISTS idx_availability_schedule_id ON availability(schedule_id);
+DROP INDEX IF EXISTS idx_availability_override_date; -- Replaced to cover date ranges
+CREATE INDEX idx_availability_override_date ON availability(user_id, override_date, override_end_date);
+CREATE INDEX IF NOT EXISTS idx_providers_team ON providers(team_id);
+CREATE INDEX IF NOT EXISTS idx_providers_user ON providers(user_id);
+CREATE INDEX IF NOT EXISTS idx_appointment_types_user_id ON appointment_types(user_id);
+
+-- Apply updated_at triggers
+DROP TRIGGER IF EXISTS update_teams_updated_at ON teams;
+CREATE TRIGGER update_teams_updated_at BEFORE UPDATE ON teams FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_providers_updated_at ON providers;
+CREATE TRIGGER update_providers_updated_at BEFORE UPDATE ON providers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_event_series_updated_at ON event_series;
+CREATE TRIGGER update_event_series_updated_at BEFORE UPDATE ON event_series FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_schedules_updated_at ON schedules;
+CREATE TRIGGER update_schedules_updated_at BEFORE UPDATE ON schedules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_appointment_types_updated_at ON appointment_types;
+CREATE TRIGGER update_appointment_types_updated_at BEFORE UPDATE ON appointment_types FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
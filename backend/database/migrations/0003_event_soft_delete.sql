@@ -0,0 +1,9 @@
+-- 0003: Soft delete for events
+-- Deleted events keep their row (the record of a medical visit) and are hidden from every read
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events(deleted_at);
+
+-- Calendar, slot and conflict queries only ever look at live events
+CREATE INDEX IF NOT EXISTS idx_events_active_created_by ON events(created_by, start_time) WHERE deleted_at IS NULL;
@@ -0,0 +1,20 @@
+-- 0004: Audit log
+-- Append-only record of every change to events, availability, schedules and user profiles
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID NOT NULL, -- No foreign key: entries must outlive the users they mention
+    actor_role VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    owner_id UUID NOT NULL, -- Provider (or user) whose data changed; used for tenant scoping
+    changes JSONB NOT NULL DEFAULT '{}', -- {"field": {"before": ..., "after": ...}}
+    ip_address TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_owner_id ON audit_log(owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
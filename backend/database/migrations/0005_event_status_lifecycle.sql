@@ -0,0 +1,12 @@
+-- 0005: Appointment status lifecycle
+-- pending -> confirmed -> checked_in -> completed | no_show, and cancellation with reason and actor
+
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_status_check;
+ALTER TABLE events ADD CONSTRAINT events_status_check
+    CHECK (status IN ('pending', 'confirmed', 'checked_in', 'completed', 'no_show', 'cancelled'));
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS cancelled_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_events_status ON events(status);
@@ -0,0 +1,26 @@
+-- 0006: Cancellation and reschedule policies
+-- Defined per provider or per team; a provider's own policy wins over their team's
+
+CREATE TABLE IF NOT EXISTS booking_policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- Provider-level policy
+    team_id UUID REFERENCES teams(id) ON DELETE CASCADE, -- Team-level policy
+    cancel_notice_minutes INTEGER NOT NULL DEFAULT 0 CHECK (cancel_notice_minutes >= 0),
+    reschedule_notice_minutes INTEGER NOT NULL DEFAULT 0 CHECK (reschedule_notice_minutes >= 0),
+    max_reschedules INTEGER CHECK (max_reschedules >= 0), -- NULL = unlimited
+    late_cancel_minutes INTEGER NOT NULL DEFAULT 0 CHECK (late_cancel_minutes >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT provider_or_team CHECK ((user_id IS NULL) <> (team_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_policies_user_id ON booking_policies(user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_policies_team_id ON booking_policies(team_id) WHERE team_id IS NOT NULL;
+
+DROP TRIGGER IF EXISTS update_booking_policies_updated_at ON booking_policies;
+CREATE TRIGGER update_booking_policies_updated_at BEFORE UPDATE ON booking_policies FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Per-appointment counters the policies are enforced against
+ALTER TABLE events ADD COLUMN IF NOT EXISTS reschedule_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS late_cancellation BOOLEAN NOT NULL DEFAULT false;
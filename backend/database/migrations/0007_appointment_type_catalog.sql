@@ -0,0 +1,22 @@
+-- 0007: Appointment type catalog
+-- Types are offered by a single provider or by every member of a team, and set the
+-- duration, buffers and calendar color of the appointments booked with them
+
+ALTER TABLE appointment_types ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE appointment_types ADD COLUMN IF NOT EXISTS team_id UUID REFERENCES teams(id) ON DELETE CASCADE;
+ALTER TABLE appointment_types ADD COLUMN IF NOT EXISTS buffer_before_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_before_minutes >= 0);
+ALTER TABLE appointment_types ADD COLUMN IF NOT EXISTS buffer_after_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_after_minutes >= 0);
+ALTER TABLE appointment_types ADD COLUMN IF NOT EXISTS color VARCHAR(9); -- Hex color, e.g. '#22c55e'
+
+ALTER TABLE appointment_types DROP CONSTRAINT IF EXISTS appointment_types_provider_or_team;
+ALTER TABLE appointment_types ADD CONSTRAINT appointment_types_provider_or_team CHECK ((user_id IS NULL) <> (team_id IS NULL));
+
+-- Schedules belong to a single provider, so team types book against each member's default schedule
+ALTER TABLE appointment_types DROP CONSTRAINT IF EXISTS appointment_types_team_default_schedule;
+ALTER TABLE appointment_types ADD CONSTRAINT appointment_types_team_default_schedule CHECK (team_id IS NULL OR schedule_id IS NULL);
+
+CREATE INDEX IF NOT EXISTS idx_appointment_types_team_id ON appointment_types(team_id);
+
+-- The type an appointment was booked as; its buffers keep time free around the appointment
+ALTER TABLE events ADD COLUMN IF NOT EXISTS appointment_type_id UUID REFERENCES appointment_types(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_events_appointment_type_id ON events(appointment_type_id);
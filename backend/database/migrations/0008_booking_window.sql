@@ -0,0 +1,8 @@
+-- 0008: Booking window and buffers
+-- How soon and how far ahead appointments can be booked, and the free time kept around
+-- every existing event, resolved like the rest of the booking policy (provider, then team)
+
+ALTER TABLE booking_policies ADD COLUMN IF NOT EXISTS min_notice_minutes INTEGER NOT NULL DEFAULT 0 CHECK (min_notice_minutes >= 0);
+ALTER TABLE booking_policies ADD COLUMN IF NOT EXISTS max_horizon_days INTEGER CHECK (max_horizon_days > 0); -- NULL = no limit
+ALTER TABLE booking_policies ADD COLUMN IF NOT EXISTS buffer_before_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_before_minutes >= 0);
+ALTER TABLE booking_policies ADD COLUMN IF NOT EXISTS buffer_after_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_after_minutes >= 0);
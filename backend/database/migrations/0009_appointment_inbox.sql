@@ -0,0 +1,22 @@
+-- 0009: Provider inbox and patient notifications
+-- Providers approve or decline pending appointment requests, or propose another time
+-- for the patient to accept; each outcome is delivered to the other party as a notification
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS proposed_start_time TIMESTAMP WITH TIME ZONE;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS proposed_end_time TIMESTAMP WITH TIME ZONE;
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_proposal_check;
+ALTER TABLE events ADD CONSTRAINT events_proposal_check
+    CHECK ((proposed_start_time IS NULL) = (proposed_end_time IS NULL) AND (proposed_end_time IS NULL OR proposed_end_time > proposed_start_time));
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Recipient
+    kind VARCHAR(50) NOT NULL, -- appointment_approved, appointment_declined, time_proposed, proposal_accepted
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
@@ -0,0 +1,62 @@
+-- 0010: Appointment waitlist
+-- Patients wait for a provider (optionally an appointment type) with date and time preferences.
+-- When time frees up, it is offered to waiting patients in order; each offer holds the slot
+-- until the patient answers or the offer expires, after which it moves on to the next patient.
+
+-- Slots reserved for one user for a limited time; other bookings treat them as taken
+CREATE TABLE IF NOT EXISTS slot_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Who the slot is held for
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT slot_holds_end_after_start CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_slot_holds_provider ON slot_holds(provider_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_slot_holds_expires_at ON slot_holds(expires_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    patient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    appointment_type_id UUID REFERENCES appointment_types(id) ON DELETE CASCADE,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    earliest_date DATE, -- NULL = from today
+    latest_date DATE, -- NULL = no limit
+    days_of_week INTEGER NOT NULL DEFAULT 0 CHECK (days_of_week BETWEEN 0 AND 127), -- Bitmask, bit 0 = Sunday; 0 = any day
+    earliest_time TIME, -- Provider's local time; NULL = any time
+    latest_time TIME,
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'offered', 'booked', 'cancelled')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT waitlist_dates_ordered CHECK (latest_date IS NULL OR earliest_date IS NULL OR latest_date >= earliest_date),
+    CONSTRAINT waitlist_times_ordered CHECK (latest_time IS NULL OR earliest_time IS NULL OR latest_time > earliest_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_provider ON waitlist_entries(provider_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_patient ON waitlist_entries(patient_id);
+
+CREATE TABLE IF NOT EXISTS waitlist_offers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entry_id UUID NOT NULL REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    hold_id UUID REFERENCES slot_holds(id) ON DELETE SET NULL,
+    event_id UUID REFERENCES events(id) ON DELETE SET NULL, -- Appointment booked when the offer is accepted
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'expired', 'withdrawn')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_waitlist_offers_entry ON waitlist_offers(entry_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_offers_pending ON waitlist_offers(expires_at) WHERE status = 'pending';
+
+DROP TRIGGER IF EXISTS update_waitlist_entries_updated_at ON waitlist_entries;
+CREATE TRIGGER update_waitlist_entries_updated_at BEFORE UPDATE ON waitlist_entries FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
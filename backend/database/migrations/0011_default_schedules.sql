@@ -0,0 +1,20 @@
+-- 0011: Default schedules for existing weekly hours
+-- Weekly rules created before named schedules have no schedule, so they were never evaluated.
+-- Every provider with such rules gets a default schedule in their profile timezone (unless
+-- they already have one) and the rules are assigned to it.
+
+INSERT INTO schedules (user_id, name, timezone, is_default)
+SELECT DISTINCT a.user_id, 'Working Hours', u.timezone, true
+FROM availability a
+JOIN users u ON u.id = a.user_id
+WHERE a.day_of_week IS NOT NULL AND a.schedule_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM schedules s WHERE s.user_id = a.user_id AND s.is_default);
+
+UPDATE availability a
+SET schedule_id = s.id
+FROM schedules s
+WHERE s.user_id = a.user_id AND s.is_default
+AND a.day_of_week IS NOT NULL AND a.schedule_id IS NULL;
+
+-- Every weekly rule now has a schedule
+ALTER TABLE availability VALIDATE CONSTRAINT recurring_needs_schedule;
@@ -0,0 +1,17 @@
+-- 0012: Team invitations
+-- Team membership is the tenant boundary, so a provider only joins a team by accepting an
+-- invitation from one of its admins.
+
+CREATE TABLE IF NOT EXISTS team_invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Invited provider or admin
+    role VARCHAR(50) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')), -- Role granted on accepting
+    is_visible BOOLEAN NOT NULL DEFAULT true,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_team_invitation UNIQUE (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_invitations_user ON team_invitations(user_id);
@@ -0,0 +1,16 @@
+-- 0013: Rolling horizon for open-ended series
+-- Open-ended series (no COUNT or UNTIL) are stored a year ahead; a background job generates
+-- further occurrences from the last generated one as time passes.
+
+ALTER TABLE event_series ADD COLUMN IF NOT EXISTS materialized_until TIMESTAMP WITH TIME ZONE; -- Start of the last occurrence generated from the rule
+
+-- Occurrences moved on their own no longer follow the rule, so they are skipped
+UPDATE event_series s
+SET materialized_until = COALESCE(
+    (SELECT MAX(e.start_time) FROM events e WHERE e.series_id = s.id AND NOT e.is_exception),
+    s.start_time)
+WHERE s.materialized_until IS NULL;
+
+ALTER TABLE event_series ALTER COLUMN materialized_until SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_event_series_open_ended ON event_series(materialized_until) WHERE until IS NULL AND count IS NULL;
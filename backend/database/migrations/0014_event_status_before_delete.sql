@@ -0,0 +1,4 @@
+-- 0014: Status of deleted events
+-- Deleting an active appointment cancels it first; restoring it brings back the status it had.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS status_before_delete VARCHAR(50); -- NULL for events deleted before this column existed
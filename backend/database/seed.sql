@@ -0,0 +1,25 @@
+-- EMR Calendar development seed data
+-- Not applied by the migrator; load manually into a migrated development database:
+--   psql "$DATABASE_URL" -f database/seed.sql
+
+-- Sample data for testing
+INSERT INTO users (id, email, full_name, role, timezone) VALUES
+('ea21c216-064b-4972-922c-5f86705df4c4', 'test@example.com', 'Test Provider', 'provider', 'UTC'),
+('f1234567-1234-1234-1234-123456789012', 'patient@example.com', 'Test Patient', 'patient', 'UTC');
+
+-- Sample default schedule
+INSERT INTO schedules (id, user_id, name, timezone, is_default) VALUES
+('5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 'ea21c216-064b-4972-922c-5f86705df4c4', 'Working Hours', 'UTC', true);
+
+-- Sample recurring availability (Mon-Fri 9-5)
+INSERT INTO availability (user_id, schedule_id, day_of_week, start_time, end_time) VALUES
+('ea21c216-064b-4972-922c-5f86705df4c4', '5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 1, '09:00', '17:00'), -- Monday
+('ea21c216-064b-4972-922c-5f86705df4c4', '5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 2, '09:00', '17:00'), -- Tuesday
+('ea21c216-064b-4972-922c-5f86705df4c4', '5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 3, '09:00', '17:00'), -- Wednesday
+('ea21c216-064b-4972-922c-5f86705df4c4', '5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 4, '09:00', '17:00'), -- Thursday
+('ea21c216-064b-4972-922c-5f86705df4c4', '5c1e0a3e-8f2b-4d7a-9b61-2f4c8e9d0a11', 5, '09:00', '17:00'); -- Friday
+
+-- Sample events
+INSERT INTO events (title, start_time, end_time, event_type, status, created_by, patient_id) VALUES
+('Doctor Appointment', '2025-09-17 10:00:00+00', '2025-09-17 11:00:00+00', 'appointment', 'confirmed', 'ea21c216-064b-4972-922c-5f86705df4c4', 'f1234567-1234-1234-1234-123456789012'),
+('Lunch Break', '2025-09-17 12:00:00+00', '2025-09-17 13:00:00+00', 'block', 'confirmed', 'ea21c216-064b-4972-922c-5f86705df4c4', NULL);
\ No newline at end of file
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/holds"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveAppointmentType loads an appointment type the provider can be booked for.
+// Writes the error response and returns false otherwise.
+func (eh *EventsHandler) resolveAppointmentType(c *gin.Context, typeID, providerID string) (*appointmenttypes.AppointmentType, bool) {
+	appointmentType, err := appointmenttypes.ForProvider(eh.db, typeID, providerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type is not offered by this provider"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+		return nil, false
+	}
+	return appointmentType, true
+}
+
+// eventAppointmentType loads the type an appointment was booked as, or nil if it has
+// none (or the type has since been deleted)
+func (eh *EventsHandler) eventAppointmentType(event *auth.Event) (*appointmenttypes.AppointmentType, error) {
+	if event.AppointmentTypeID == nil {
+		return nil, nil
+	}
+
+	appointmentType, err := appointmenttypes.Load(eh.db, *event.AppointmentTypeID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return appointmentType, err
+}
+
+// bookingChecker returns a conflict checker running inside tx that books against the
+// appointment type's schedule and keeps its buffers free (appointmentType may be nil)
+func (eh *EventsHandler) bookingChecker(tx *sql.Tx, userCtx *auth.UserContext, appointmentType *appointmenttypes.AppointmentType) *conflicts.ConflictChecker {
+	checker := conflicts.NewConflictChecker(eh.db).WithScope(userCtx).InTx(tx)
+	if appointmentType != nil {
+		checker.WithBuffers(appointmentType.BufferBeforeMinutes, appointmentType.BufferAfterMinutes)
+		if appointmentType.ScheduleID != nil {
+			checker.WithSchedule(*appointmentType.ScheduleID)
+		}
+	}
+	return checker
+}
+
+// consumeHold releases the caller's hold on the provider's slot from start to end inside tx, so
+// the booking that replaces it does not conflict with it. Call it after locking the provider.
+// Writes the error response and returns false if the hold is not the caller's, does not cover
+// the slot, has expired or belongs to a waitlist offer.
+func (eh *EventsHandler) consumeHold(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, holdID, providerID string, start, end time.Time) bool {
+	hold, err := holds.Lock(tx, holdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hold"})
+		return false
+	}
+	if hold.UserID != userCtx.UserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return false
+	}
+
+	if !hold.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hold has expired"})
+		return false
+	}
+	if !hold.Covers(providerID, start, end) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hold does not cover this time slot"})
+		return false
+	}
+
+	var offered bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM waitlist_offers WHERE hold_id = $1)`, holdID).Scan(&offered); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hold"})
+		return false
+	}
+	if offered {
+		c.JSON(http.StatusConflict, gin.H{"error": "This slot is held for a waitlist offer; accept the offer to book it"})
+		return false
+	}
+
+	if err := holds.Release(tx, holdID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release held slot"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,275 @@
+package events
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/availability"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/gin-gonic/gin"
+)
+
+// noShowLookbackDays is how far back the provider dashboard's no-show rate looks
+const noShowLookbackDays = 90
+
+// dashboardSlotMinutes is the slot length open slots are counted in
+const dashboardSlotMinutes = 30
+
+// ProviderDashboard returns the provider's key figures: today's visits, this week's
+// utilization (booked appointment time as a percentage of available time) and open
+// 30-minute slots, the no-show rate over the last 90 days, and pending requests.
+// Days and weeks (Monday to Sunday) follow the timezone of the provider's default schedule.
+func (eh *EventsHandler) ProviderDashboard(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	providerID := userCtx.UserID
+
+	schedule, err := conflicts.LoadSchedule(eh.db, providerID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load schedule", "details": err.Error()})
+		return
+	}
+	loc := schedule.Location
+
+	now := time.Now().UTC()
+	today := timezone.Date(now, loc)
+	todayStart, todayEnd := timezone.StartOfDay(today, loc)
+	weekStartDate := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	weekEndDate := weekStartDate.AddDate(0, 0, 6)
+	weekStart, _ := timezone.StartOfDay(weekStartDate, loc)
+	_, weekEnd := timezone.StartOfDay(weekEndDate, loc)
+
+	// Today's visits by status
+	visitsByStatus, err := eh.countByStatus(`
+		SELECT status, COUNT(*)
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status != $2
+		AND start_time >= $3 AND start_time < $4 AND deleted_at IS NULL
+		GROUP BY status`,
+		providerID, StatusCancelled, todayStart, todayEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count today's visits"})
+		return
+	}
+	visits := 0
+	for _, count := range visitsByStatus {
+		visits += count
+	}
+
+	// Utilization: booked appointment minutes within the week against available minutes
+	windowsByDate, err := conflicts.NewConflictChecker(eh.db).RangeWindows(providerID, schedule, weekStartDate, weekEndDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load availability", "details": err.Error()})
+		return
+	}
+	availableMinutes := 0.0
+	for _, windows := range windowsByDate {
+		for _, window := range windows {
+			availableMinutes += window.End.Sub(window.Start).Minutes()
+		}
+	}
+
+	var bookedMinutes float64
+	err = eh.db.QueryRow(`
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(end_time, $4) - GREATEST(start_time, $3))) / 60), 0)::float8
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status != $2
+		AND start_time < $4 AND end_time > $3 AND deleted_at IS NULL`,
+		providerID, StatusCancelled, weekStart, weekEnd).Scan(&bookedMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute utilization"})
+		return
+	}
+
+	// Open slots from today to the end of the week (past slots are never open)
+	days, err := availability.OpenSlots(eh.db, providerID, schedule, today, weekEndDate, dashboardSlotMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count open slots", "details": err.Error()})
+		return
+	}
+	openSlots := 0
+	for _, day := range days {
+		openSlots += day.Total
+	}
+
+	// No-show rate among appointments that have been attended or missed
+	var noShows, concluded int
+	err = eh.db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE status = $2), COUNT(*)
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status IN ($2, $3)
+		AND start_time >= $4 AND start_time < $5 AND deleted_at IS NULL`,
+		providerID, StatusNoShow, StatusCompleted, now.AddDate(0, 0, -noShowLookbackDays), now).Scan(&noShows, &concluded)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute no-show rate"})
+		return
+	}
+
+	var pendingRequests int
+	err = eh.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status = $2 AND end_time > $3 AND deleted_at IS NULL`,
+		providerID, StatusPending, now).Scan(&pendingRequests)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count pending requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone": loc.String(),
+		"today": gin.H{
+			"date":      today.Format("2006-01-02"),
+			"visits":    visits,
+			"by_status": visitsByStatus,
+		},
+		"week": gin.H{
+			"start_date":        weekStartDate.Format("2006-01-02"),
+			"end_date":          weekEndDate.Format("2006-01-02"),
+			"available_minutes": int(availableMinutes),
+			"booked_minutes":    int(bookedMinutes),
+			"utilization":       percentage(bookedMinutes, availableMinutes),
+			"open_slots":        openSlots,
+			"slot_minutes":      dashboardSlotMinutes,
+		},
+		"no_shows": gin.H{
+			"days":      noShowLookbackDays,
+			"no_shows":  noShows,
+			"concluded": concluded,
+			"rate":      percentage(float64(noShows), float64(concluded)),
+		},
+		"pending_requests": pendingRequests,
+	})
+}
+
+// PatientDashboard returns the patient's next confirmed appointment, their pending requests
+// (including any time a provider has proposed) and their appointment history, most recent first
+// (limit, default 10), with totals by status
+func (eh *EventsHandler) PatientDashboard(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	patientID := userCtx.UserID
+	now := time.Now().UTC()
+
+	upcoming, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE patient_id = $1 AND event_type = 'appointment' AND status IN ($2, $3) AND end_time > $4 AND deleted_at IS NULL
+		ORDER BY start_time ASC
+		LIMIT 1`,
+		patientID, StatusConfirmed, StatusCheckedIn, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch next appointment"})
+		return
+	}
+
+	var next gin.H
+	if len(upcoming) > 0 {
+		var providerName string
+		err := eh.db.QueryRow(`SELECT full_name FROM users WHERE id = $1`, upcoming[0].CreatedBy).Scan(&providerName)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch provider"})
+			return
+		}
+		next = gin.H{"event": upcoming[0], "provider_name": providerName}
+	}
+
+	pending, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE patient_id = $1 AND event_type = 'appointment' AND status = $2 AND end_time > $3 AND deleted_at IS NULL
+		ORDER BY start_time ASC`,
+		patientID, StatusPending, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending requests"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	// History: appointments that are over, or were cancelled
+	history, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE patient_id = $1 AND event_type = 'appointment' AND (end_time <= $2 OR status = $3) AND deleted_at IS NULL
+		ORDER BY start_time DESC
+		LIMIT $4`,
+		patientID, now, StatusCancelled, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment history"})
+		return
+	}
+
+	historyByStatus, err := eh.countByStatus(`
+		SELECT status, COUNT(*)
+		FROM events
+		WHERE patient_id = $1 AND event_type = 'appointment' AND (end_time <= $2 OR status = $3) AND deleted_at IS NULL
+		GROUP BY status`,
+		patientID, now, StatusCancelled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count appointment history"})
+		return
+	}
+	total := 0
+	for _, count := range historyByStatus {
+		total += count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"next_appointment": next,
+		"pending_requests": pending,
+		"history": gin.H{
+			"appointments": history,
+			"total":        total,
+			"by_status":    historyByStatus,
+		},
+	})
+}
+
+// countByStatus runs a query selecting (status, count) pairs
+func (eh *EventsHandler) countByStatus(query string, args ...interface{}) (map[string]int, error) {
+	rows, err := eh.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// percentage returns part as a percentage of whole rounded to one decimal, or nil when whole is zero
+func percentage(part, whole float64) *float64 {
+	if whole <= 0 {
+		return nil
+	}
+	value := math.Round(part/whole*1000) / 10
+	return &value
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDeletedEvents lists soft deleted events of providers in the admin's teams, most recently deleted first
+func (eh *EventsHandler) GetDeletedEvents(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view deleted events"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	scope, scopeArgs := userCtx.ProviderScope("created_by", 1)
+	args := scopeArgs
+	argIndex := 1 + len(scopeArgs)
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events
+		WHERE deleted_at IS NOT NULL AND ` + scope
+
+	// Optionally narrow the list to one provider
+	if providerID := c.Query("provider_id"); providerID != "" {
+		query += fmt.Sprintf(" AND created_by = $%d", argIndex)
+		args = append(args, providerID)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(" ORDER BY deleted_at DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := eh.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted events", "details": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	events := []auth.Event{}
+	for rows.Next() {
+		var event auth.Event
+		if err := scanEvent(rows, &event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan event"})
+			return
+		}
+		events = append(events, event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(events),
+		},
+	})
+}
+
+// RestoreEvent brings back a soft deleted event with the status it had before it was deleted,
+// undoing the cancellation that deleting an active appointment records. Appointments that
+// come back active are re-checked against the provider's calendar, since the slot may have
+// been booked since.
+func (eh *EventsHandler) RestoreEvent(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	eventID := c.Param("id")
+
+	if userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can restore events"})
+		return
+	}
+
+	scope, scopeArgs := userCtx.ProviderScope("created_by", 2)
+	args := append([]interface{}{eventID}, scopeArgs...)
+
+	var providerID string
+	err := eh.db.QueryRow(`SELECT created_by FROM events WHERE id = $1 AND deleted_at IS NOT NULL AND `+scope, args...).Scan(&providerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	// Start transaction so the conflict check and the restore happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the event row, as every change that takes up time does
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	var event auth.Event
+	err = scanEvent(tx.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, eventID), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	var statusBeforeDelete sql.NullString
+	if err := tx.QueryRow(`SELECT status_before_delete FROM events WHERE id = $1`, eventID).Scan(&statusBeforeDelete); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	// Only a cancellation made by the deletion itself is undone
+	status := event.Status
+	uncancel := event.Status == StatusCancelled && statusBeforeDelete.Valid && statusBeforeDelete.String != StatusCancelled
+	if uncancel {
+		status = statusBeforeDelete.String
+	}
+
+	if event.EventType == "appointment" && status != StatusCancelled {
+		appointmentType, err := eh.eventAppointmentType(&event)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
+
+		// Restoring brings back an existing booking, so the booking window does not apply
+		conflictChecker := eh.bookingChecker(tx, userCtx, appointmentType).IgnoreBookingWindow()
+		conflictResult, err := conflictChecker.CheckTimeSlotAvailability(event.CreatedBy, event.StartTime, event.EndTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+			return
+		}
+
+		if conflictResult.HasConflict {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":                 "Time slot not available",
+				"conflict_type":         conflictResult.ConflictType,
+				"message":               conflictResult.Message,
+				"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+			})
+			return
+		}
+	}
+
+	query := `
+		UPDATE events
+		SET deleted_at = NULL, status_before_delete = NULL, updated_at = $2
+		WHERE id = $1
+		RETURNING ` + eventColumns
+	restoreArgs := []interface{}{eventID, time.Now().UTC()}
+
+	if uncancel {
+		query = `
+			UPDATE events
+			SET deleted_at = NULL, status_before_delete = NULL, updated_at = $2, status = $3,
+			    cancellation_reason = NULL, cancelled_by = NULL, cancelled_at = NULL, late_cancellation = false
+			WHERE id = $1
+			RETURNING ` + eventColumns
+		restoreArgs = append(restoreArgs, status)
+	}
+
+	var restored auth.Event
+	if err = scanEvent(tx.QueryRow(query, restoreArgs...), &restored); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore event"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionRestore, auditlog.EntityEvent, eventID, restored.CreatedBy, event, restored); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": restored})
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"emr-calendar-backend/appointmenttypes"
 	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
 	"emr-calendar-backend/lib/conflicts"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +19,27 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// eventColumns lists the columns selected for every event, in the order scanEvent expects
+const eventColumns = `id, title, description, start_time, end_time, event_type, status,
+	created_by, patient_id, appointment_type_id, series_id, is_exception, reschedule_count, late_cancellation,
+	cancellation_reason, cancelled_by, cancelled_at, proposed_start_time, proposed_end_time, created_at, updated_at, deleted_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEvent scans a row selected with eventColumns into event
+func scanEvent(row rowScanner, event *auth.Event) error {
+	return row.Scan(
+		&event.ID, &event.Title, &event.Description, &event.StartTime, &event.EndTime,
+		&event.EventType, &event.Status, &event.CreatedBy, &event.PatientID, &event.AppointmentTypeID,
+		&event.SeriesID, &event.IsException, &event.RescheduleCount, &event.LateCancellation,
+		&event.CancellationReason, &event.CancelledBy, &event.CancelledAt, &event.ProposedStartTime, &event.ProposedEndTime,
+		&event.CreatedAt, &event.UpdatedAt, &event.DeletedAt,
+	)
+}
+
 type EventsHandler struct {
 	db *sql.DB
 }
@@ -40,6 +63,7 @@ func (eh *EventsHandler) GetEvents(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 	eventType := c.Query("event_type")
+	seriesID := c.Query("series_id")
 	limitStr := c.DefaultQuery("limit", "50")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -62,22 +86,21 @@ func (eh *EventsHandler) GetEvents(c *gin.Context) {
 	var argIndex int
 
 	// Role-based filtering:
-	// - Admin users can see all events
+	// - Admin users can see all events of providers in their teams
 	// - Other users only see events where they are either the creator OR the patient
 	if userCtx.UserRole == "admin" {
+		scope, scopeArgs := userCtx.ProviderScope("created_by", 1)
 		query = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
+			SELECT ` + eventColumns + `
 			FROM events
-			WHERE 1=1`
-		args = []interface{}{}
-		argIndex = 1
+			WHERE deleted_at IS NULL AND ` + scope
+		args = scopeArgs
+		argIndex = 1 + len(scopeArgs)
 	} else {
 		query = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
+			SELECT ` + eventColumns + `
 			FROM events
-			WHERE (created_by = $1 OR patient_id = $1)`
+			WHERE deleted_at IS NULL AND (created_by = $1 OR patient_id = $1)`
 		args = []interface{}{userCtx.UserID}
 		argIndex = 2
 	}
@@ -100,6 +123,13 @@ func (eh *EventsHandler) GetEvents(c *gin.Context) {
 		argIndex++
 	}
 
+	// Recurring series filtering (occurrences are stored as events, so date ranges include them)
+	if seriesID != "" {
+		query += fmt.Sprintf(" AND series_id = $%d", argIndex)
+		args = append(args, seriesID)
+		argIndex++
+	}
+
 	// Add ordering and pagination
 	query += " ORDER BY start_time ASC"
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
@@ -118,11 +148,7 @@ func (eh *EventsHandler) GetEvents(c *gin.Context) {
 	var events []auth.Event
 	for rows.Next() {
 		var event auth.Event
-		err := rows.Scan(
-			&event.ID, &event.Title, &event.Description, &event.StartTime, &event.EndTime,
-			&event.EventType, &event.Status, &event.CreatedBy, &event.PatientID,
-			&event.CreatedAt, &event.UpdatedAt,
-		)
+		err := scanEvent(rows, &event)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan event"})
 			return
@@ -153,13 +179,72 @@ func (eh *EventsHandler) CreateEvent(c *gin.Context) {
 		return
 	}
 
+	// Patients book through BookAppointment, which only accepts published slots
+	if userCtx.UserRole == "patient" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Patients must book appointments through /api/v1/patient/appointments"})
+		return
+	}
+
 	var req auth.CreateEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
 		return
 	}
 
+	// Only admins may book into another provider's calendar
+	if userCtx.UserRole != "admin" && req.ProviderID != nil && *req.ProviderID != "" && *req.ProviderID != userCtx.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create events for other providers"})
+		return
+	}
+
+	// Admins may only create events for providers within their own teams
+	if userCtx.UserRole == "admin" && req.ProviderID != nil && *req.ProviderID != "" {
+		allowed, err := userCtx.CanAccessProvider(eh.db, *req.ProviderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
+			return
+		}
+	}
+
+	// Determine who should be the creator based on role and request; the event is
+	// checked against and stored in this provider's calendar
+	createdBy := resolveCreatedBy(userCtx, &req)
+
+	// An appointment type fills in the title and end time and sets the schedule and buffers
+	var appointmentType *appointmenttypes.AppointmentType
+	if req.AppointmentTypeID != nil && *req.AppointmentTypeID != "" {
+		if req.EventType != "appointment" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only appointments can have an appointment type"})
+			return
+		}
+
+		var ok bool
+		if appointmentType, ok = eh.resolveAppointmentType(c, *req.AppointmentTypeID, createdBy); !ok {
+			return
+		}
+		if strings.TrimSpace(req.Title) == "" {
+			req.Title = appointmentType.Name
+		}
+		if req.EndTime.IsZero() {
+			req.EndTime = req.StartTime.Add(time.Duration(appointmentType.DurationMinutes) * time.Minute)
+		}
+	} else {
+		req.AppointmentTypeID = nil
+	}
+
 	// Validate business logic
+	if strings.TrimSpace(req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+	if req.EndTime.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End time is required unless an appointment type is given"})
+		return
+	}
 	if req.EndTime.Before(req.StartTime) || req.EndTime.Equal(req.StartTime) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
 		return
@@ -173,23 +258,57 @@ func (eh *EventsHandler) CreateEvent(c *gin.Context) {
 
 	// Set default status if not provided
 	if req.Status == "" {
-		req.Status = "pending"
+		req.Status = StatusPending
+	}
+
+	// Booking straight into confirmed needs the same permission as confirming later
+	if req.Status == StatusConfirmed && !findAction("confirm").permits(userCtx.UserRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot confirm appointments"})
+		return
+	}
+
+	// A held slot is a single appointment
+	holdID := ""
+	if req.HoldID != nil && *req.HoldID != "" {
+		if req.EventType != "appointment" || req.Recurrence != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only a single appointment can be booked into a held slot"})
+			return
+		}
+		holdID = *req.HoldID
+	}
+
+	// Recurring series are expanded and conflict-checked occurrence by occurrence
+	if req.Recurrence != nil {
+		eh.createSeries(c, userCtx, &req, createdBy, appointmentType)
+		return
 	}
 
+	// Start transaction so the conflict check and the insert happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
 	// Check availability conflicts before creating the event
 	// Only check conflicts for appointments (not for blocks)
 	if req.EventType == "appointment" {
-		var providerID string
-		if req.ProviderID != nil && *req.ProviderID != "" {
-			providerID = *req.ProviderID
-		} else {
-			// If no specific provider in request, use the user creating the event
-			providerID = userCtx.UserID
+		// Serialize bookings for this provider; a request that loses the race
+		// waits here and then sees the winner's event as an overlap
+		if err := conflicts.LockProvider(tx, createdBy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+			return
 		}
 
-		conflictChecker := conflicts.NewConflictChecker(eh.db)
+		// Booking a held slot releases the hold first so it does not conflict with itself
+		if holdID != "" && !eh.consumeHold(c, tx, userCtx, holdID, createdBy, req.StartTime, req.EndTime) {
+			return
+		}
+
+		conflictChecker := eh.bookingChecker(tx, userCtx, appointmentType)
 		conflictResult, err := conflictChecker.CheckTimeSlotAvailability(
-			providerID,
+			createdBy,
 			req.StartTime,
 			req.EndTime,
 		)
@@ -207,56 +326,60 @@ func (eh *EventsHandler) CreateEvent(c *gin.Context) {
 				"error": "Time slot not available",
 				"conflict_type": conflictResult.ConflictType,
 				"message": conflictResult.Message,
+				"conflicting_event_ids": conflictResult.ConflictingEventIDs,
 			})
 			return
 		}
 	}
 
-	// Determine who should be the creator based on role and request
-	var createdBy string
-	if userCtx.UserRole == "admin" && req.ProviderID != nil && *req.ProviderID != "" {
-		// Admin creating event for a specific provider
-		createdBy = *req.ProviderID
-	} else if userCtx.UserRole == "patient" && req.ProviderID != nil && *req.ProviderID != "" {
-		// Patient creating appointment with a specific provider
-		createdBy = *req.ProviderID
-	} else {
-		// Provider creating their own event, or admin without specific provider
-		createdBy = userCtx.UserID
-	}
-
 	// Generate UUID for event
 	eventID := uuid.New().String()
 
 	// Insert into database
 	query := `
 		INSERT INTO events (id, title, description, start_time, end_time, event_type, status,
-		                   created_by, patient_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, title, description, start_time, end_time, event_type, status,
-		          created_by, patient_id, created_at, updated_at`
+		                   created_by, patient_id, appointment_type_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + eventColumns
 
 	var event auth.Event
 	now := time.Now().UTC()
-	err := eh.db.QueryRow(
+	err = scanEvent(tx.QueryRow(
 		query,
 		eventID, req.Title, req.Description, req.StartTime, req.EndTime,
-		req.EventType, req.Status, createdBy, req.PatientID,
+		req.EventType, req.Status, createdBy, req.PatientID, req.AppointmentTypeID,
 		now, now,
-	).Scan(
-		&event.ID, &event.Title, &event.Description, &event.StartTime, &event.EndTime,
-		&event.EventType, &event.Status, &event.CreatedBy, &event.PatientID,
-		&event.CreatedAt, &event.UpdatedAt,
-	)
+	), &event)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
 		return
 	}
 
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionCreate, auditlog.EntityEvent, event.ID, event.CreatedBy, nil, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"event": event})
 }
 
+// resolveCreatedBy determines who should be the creator based on role and request
+func resolveCreatedBy(userCtx *auth.UserContext, req *auth.CreateEventRequest) string {
+	if userCtx.UserRole == "admin" && req.ProviderID != nil && *req.ProviderID != "" {
+		// Admin creating event for a specific provider
+		return *req.ProviderID
+	}
+	// Provider creating their own event, or admin without specific provider
+	return userCtx.UserID
+}
+
 // GetEvent retrieves a specific event by ID
 func (eh *EventsHandler) GetEvent(c *gin.Context) {
 	userCtx, exists := auth.GetUserContext(c)
@@ -266,33 +389,7 @@ func (eh *EventsHandler) GetEvent(c *gin.Context) {
 	}
 	eventID := c.Param("id")
 
-	// Role-based access control - same as GetEvents
-	var query string
-	var args []interface{}
-
-	if userCtx.UserRole == "admin" {
-		query = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
-			FROM events
-			WHERE id = $1`
-		args = []interface{}{eventID}
-	} else {
-		query = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
-			FROM events
-			WHERE id = $1 AND (created_by = $2 OR patient_id = $2)`
-		args = []interface{}{eventID, userCtx.UserID}
-	}
-
-	var event auth.Event
-	err := eh.db.QueryRow(query, args...).Scan(
-		&event.ID, &event.Title, &event.Description, &event.StartTime, &event.EndTime,
-		&event.EventType, &event.Status, &event.CreatedBy, &event.PatientID,
-		&event.CreatedAt, &event.UpdatedAt,
-	)
-
+	event, err := eh.getAccessibleEvent(userCtx, eventID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
@@ -320,34 +417,57 @@ func (eh *EventsHandler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	// Recurring occurrences can be edited alone ("this") or together with every later one ("following")
+	seriesScope := c.DefaultQuery("scope", "this")
+	if seriesScope != "this" && seriesScope != "following" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope, must be 'this' or 'following'"})
+		return
+	}
+
+	override, ok := policyOverride(c, userCtx)
+	if !ok {
+		return
+	}
+
 	// First, check if event exists and user has access to it
-	var existingEvent auth.Event
-	var checkQuery string
-	var checkArgs []interface{}
+	existingEvent, err := eh.getAccessibleEvent(userCtx, eventID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
 
-	if userCtx.UserRole == "admin" {
-		checkQuery = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
-			FROM events
-			WHERE id = $1`
-		checkArgs = []interface{}{eventID}
-	} else {
-		checkQuery = `
-			SELECT id, title, description, start_time, end_time, event_type, status,
-			       created_by, patient_id, created_at, updated_at
-			FROM events
-			WHERE id = $1 AND (created_by = $2 OR patient_id = $2)`
-		checkArgs = []interface{}{eventID, userCtx.UserID}
+	// Each occurrence has its own place in the lifecycle, so a status applies to one occurrence only
+	if req.Status != nil && seriesScope == "following" && existingEvent.SeriesID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status can only be changed for a single occurrence"})
+		return
 	}
 
-	err := eh.db.QueryRow(checkQuery, checkArgs...).Scan(
-		&existingEvent.ID, &existingEvent.Title, &existingEvent.Description,
-		&existingEvent.StartTime, &existingEvent.EndTime, &existingEvent.EventType,
-		&existingEvent.Status, &existingEvent.CreatedBy, &existingEvent.PatientID,
-		&existingEvent.CreatedAt, &existingEvent.UpdatedAt,
-	)
+	if seriesScope == "following" && existingEvent.SeriesID != nil {
+		eh.updateFollowing(c, userCtx, existingEvent, &req, override)
+		return
+	}
+
+	// Start transaction so the policy and conflict checks and the update happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the event row, as every change to it does
+	if err := conflicts.LockProvider(tx, existingEvent.CreatedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
 
+	// Re-read the event under a row lock so the checks below see its current status and times
+	var event auth.Event
+	err = scanEvent(tx.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, existingEvent.ID), &event)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
@@ -356,6 +476,78 @@ func (eh *EventsHandler) UpdateEvent(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
 		return
 	}
+	existingEvent = &event
+
+	// Status changes follow the appointment lifecycle; cancelling goes through
+	// POST /events/:id/cancel so that a reason is recorded
+	if req.Status != nil && *req.Status != existingEvent.Status {
+		if *req.Status == StatusCancelled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /events/:id/cancel to cancel an appointment"})
+			return
+		}
+		if code, message := validateTransition(userCtx, existingEvent.Status, *req.Status); code != 0 {
+			c.JSON(code, gin.H{"error": message})
+			return
+		}
+	}
+
+	// Resolve the event as it will look after the update and validate it before writing
+	newStart, newEnd := existingEvent.StartTime, existingEvent.EndTime
+	if req.StartTime != nil {
+		newStart = *req.StartTime
+	}
+	if req.EndTime != nil {
+		newEnd = *req.EndTime
+	}
+	if !newEnd.After(newStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
+		return
+	}
+
+	newType, newStatus := existingEvent.EventType, existingEvent.Status
+	if req.EventType != nil {
+		newType = *req.EventType
+	}
+	if req.Status != nil {
+		newStatus = *req.Status
+	}
+
+	// Moving an active appointment must respect the provider's reschedule policy
+	rescheduled := isActiveAppointment(existingEvent) && (!newStart.Equal(existingEvent.StartTime) || !newEnd.Equal(existingEvent.EndTime))
+	if rescheduled && !checkReschedulePolicy(c, tx, existingEvent.CreatedBy, []auth.Event{*existingEvent}, override) {
+		return
+	}
+
+	// Re-check availability whenever an active appointment moves, or an event becomes one
+	timesChanged := !newStart.Equal(existingEvent.StartTime) || !newEnd.Equal(existingEvent.EndTime)
+	becameActive := existingEvent.EventType != "appointment" || existingEvent.Status == StatusCancelled
+	if newType == "appointment" && newStatus != StatusCancelled && (timesChanged || becameActive) {
+		appointmentType, err := eh.eventAppointmentType(existingEvent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
+
+		conflictChecker := eh.bookingChecker(tx, userCtx, appointmentType).ExcludeEvents(eventID)
+		conflictResult, err := conflictChecker.CheckTimeSlotAvailability(existingEvent.CreatedBy, newStart, newEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check availability",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if conflictResult.HasConflict {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Time slot not available",
+				"conflict_type": conflictResult.ConflictType,
+				"message": conflictResult.Message,
+				"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+			})
+			return
+		}
+	}
 
 	// Build dynamic update query
 	updateFields := []string{}
@@ -409,6 +601,15 @@ func (eh *EventsHandler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	// Moving a single occurrence detaches it from its series pattern
+	if existingEvent.SeriesID != nil && (req.StartTime != nil || req.EndTime != nil) {
+		updateFields = append(updateFields, "is_exception = true")
+	}
+
+	if rescheduled {
+		updateFields = append(updateFields, "reschedule_count = reschedule_count + 1")
+	}
+
 	// Add updated_at field
 	updateFields = append(updateFields, fmt.Sprintf("updated_at = $%d", argIndex))
 	args = append(args, time.Now().UTC())
@@ -417,45 +618,50 @@ func (eh *EventsHandler) UpdateEvent(c *gin.Context) {
 	// Add WHERE condition based on role
 	var whereClause string
 	if userCtx.UserRole == "admin" {
+		scope, scopeArgs := userCtx.ProviderScope("created_by", argIndex+1)
 		args = append(args, eventID)
-		whereClause = fmt.Sprintf("WHERE id = $%d", argIndex)
+		args = append(args, scopeArgs...)
+		whereClause = fmt.Sprintf("WHERE id = $%d AND deleted_at IS NULL AND %s", argIndex, scope)
 	} else {
 		args = append(args, eventID, userCtx.UserID)
-		whereClause = fmt.Sprintf("WHERE id = $%d AND (created_by = $%d OR patient_id = $%d)", argIndex, argIndex+1, argIndex+1)
+		whereClause = fmt.Sprintf("WHERE id = $%d AND deleted_at IS NULL AND (created_by = $%d OR patient_id = $%d)", argIndex, argIndex+1, argIndex+1)
 	}
 
 	updateQuery := fmt.Sprintf(`
 		UPDATE events
 		SET %s
 		%s
-		RETURNING id, title, description, start_time, end_time, event_type, status,
-		          created_by, patient_id, created_at, updated_at`,
+		RETURNING %s`,
 		strings.Join(updateFields, ", "),
-		whereClause)
+		whereClause,
+		eventColumns)
 
 	var updatedEvent auth.Event
-	err = eh.db.QueryRow(updateQuery, args...).Scan(
-		&updatedEvent.ID, &updatedEvent.Title, &updatedEvent.Description,
-		&updatedEvent.StartTime, &updatedEvent.EndTime, &updatedEvent.EventType,
-		&updatedEvent.Status, &updatedEvent.CreatedBy, &updatedEvent.PatientID,
-		&updatedEvent.CreatedAt, &updatedEvent.UpdatedAt,
-	)
+	err = scanEvent(tx.QueryRow(updateQuery, args...), &updatedEvent)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
 
-	// Validate business logic after update
-	if updatedEvent.EndTime.Before(updatedEvent.StartTime) || updatedEvent.EndTime.Equal(updatedEvent.StartTime) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityEvent, eventID, updatedEvent.CreatedBy, existingEvent, updatedEvent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"event": updatedEvent})
 }
 
-// DeleteEvent deletes an existing event
+// DeleteEvent soft deletes an existing event. The row is kept (it may be the record of a
+// medical visit) and can be brought back by an admin with RestoreEvent. A pending or
+// confirmed appointment is cancelled first, with the optional reason query parameter, and
+// its status is kept for the restore.
 func (eh *EventsHandler) DeleteEvent(c *gin.Context) {
 	userCtx, exists := auth.GetUserContext(c)
 	if !exists || userCtx == nil {
@@ -464,34 +670,125 @@ func (eh *EventsHandler) DeleteEvent(c *gin.Context) {
 	}
 	eventID := c.Param("id")
 
-	// Role-based access control for deletion
-	var query string
-	var args []interface{}
+	seriesScope := c.DefaultQuery("scope", "this")
+	if seriesScope != "this" && seriesScope != "following" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope, must be 'this' or 'following'"})
+		return
+	}
 
-	if userCtx.UserRole == "admin" {
-		query = `DELETE FROM events WHERE id = $1`
-		args = []interface{}{eventID}
-	} else {
-		query = `DELETE FROM events WHERE id = $1 AND (created_by = $2 OR patient_id = $2)`
-		args = []interface{}{eventID, userCtx.UserID}
+	override, ok := policyOverride(c, userCtx)
+	if !ok {
+		return
 	}
 
-	result, err := eh.db.Exec(query, args...)
+	if seriesScope == "following" {
+		eh.deleteFollowing(c, userCtx, eventID, override)
+		return
+	}
+
+	existingEvent, err := eh.getAccessibleEvent(userCtx, eventID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// Start transaction so the cancellation, the deletion and their audit entries are recorded together
+	tx, err := eh.db.Begin()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the event row, as every change that frees time does
+	if err := conflicts.LockProvider(tx, existingEvent.CreatedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
 		return
 	}
 
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	// Re-read the event under a row lock so a concurrent status change cannot slip in
+	var event auth.Event
+	err = scanEvent(tx.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, existingEvent.ID), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	actor := userCtx.AuditActor(c)
+	freesTime := event.Status != StatusCancelled
+	statusBeforeDelete := event.Status
+
+	// Deleting an active appointment cancels it first, so it records who cancelled it, when and why
+	if isActiveAppointment(&event) {
+		late, ok := checkCancellationPolicy(c, tx, &event, override)
+		if !ok || !cancelForDeletion(c, tx, userCtx, actor, &event, deletionReason(c), late) {
+			return
+		}
+	}
+
+	var deleted auth.Event
+	now := time.Now().UTC()
+	err = scanEvent(tx.QueryRow(`
+		UPDATE events SET deleted_at = $2, updated_at = $2, status_before_delete = $3
+		WHERE id = $1
+		RETURNING `+eventColumns,
+		event.ID, now, statusBeforeDelete), &deleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+
+	if err = auditlog.Record(tx, actor, auditlog.ActionDelete, auditlog.EntityEvent, eventID, deleted.CreatedBy, event, deleted); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// The deleted event's time is free for the waitlist
+	if freesTime && !eh.offerFreedTime(c, tx, &deleted) {
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Event deleted successfully"})
-}
\ No newline at end of file
+}
+
+// getAccessibleEvent loads an event the user is allowed to see, returning sql.ErrNoRows otherwise.
+// Admins can access any event of providers in their teams; other users only their own events.
+func (eh *EventsHandler) getAccessibleEvent(userCtx *auth.UserContext, eventID string) (*auth.Event, error) {
+	var query string
+	var args []interface{}
+
+	if userCtx.UserRole == "admin" {
+		scope, scopeArgs := userCtx.ProviderScope("created_by", 2)
+		query = `
+			SELECT ` + eventColumns + `
+			FROM events
+			WHERE id = $1 AND deleted_at IS NULL AND ` + scope
+		args = append([]interface{}{eventID}, scopeArgs...)
+	} else {
+		query = `
+			SELECT ` + eventColumns + `
+			FROM events
+			WHERE id = $1 AND deleted_at IS NULL AND (created_by = $2 OR patient_id = $2)`
+		args = []interface{}{eventID, userCtx.UserID}
+	}
+
+	event := &auth.Event{}
+	if err := scanEvent(eh.db.QueryRow(query, args...), event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
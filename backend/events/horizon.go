@@ -0,0 +1,203 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/notify"
+	"emr-calendar-backend/lib/recurrence"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/google/uuid"
+)
+
+// systemRole is the actor role recorded for changes made by background jobs
+const systemRole = "system"
+
+// ExtendSeries stores the occurrences of every open-ended series (no COUNT or UNTIL) up to
+// recurrence.DefaultHorizon from now. Each series is extended in its own transaction, so a
+// failure only delays that series until the next run.
+func ExtendSeries(db *sql.DB) error {
+	horizon := time.Now().UTC().Add(recurrence.DefaultHorizon)
+
+	rows, err := db.Query(`
+		SELECT id FROM event_series
+		WHERE until IS NULL AND count IS NULL AND materialized_until < $1`,
+		horizon)
+	if err != nil {
+		return err
+	}
+
+	var seriesIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		seriesIDs = append(seriesIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range seriesIDs {
+		if err := extendSeries(db, id, horizon); err != nil {
+			log.Printf("Warning: Failed to extend event series %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// SweepSeries extends open-ended series every interval. It runs until the process exits.
+func SweepSeries(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := ExtendSeries(db); err != nil {
+			log.Printf("Warning: Failed to extend event series: %v", err)
+		}
+	}
+}
+
+// extendSeries stores the occurrences of a series after its last generated one, up to horizon.
+// New occurrences copy the latest active occurrence. Appointment occurrences that clash with
+// the provider's calendar are skipped and the provider is notified.
+func extendSeries(db *sql.DB, seriesID string, horizon time.Time) error {
+	var providerID string
+	if err := db.QueryRow(`SELECT created_by FROM event_series WHERE id = $1`, seriesID).Scan(&providerID); err != nil {
+		return err
+	}
+
+	// Start transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		return err
+	}
+
+	var series auth.EventSeries
+	err = scanSeries(tx.QueryRow(`SELECT `+seriesColumns+` FROM event_series WHERE id = $1 FOR UPDATE`, seriesID), &series)
+	if err != nil {
+		return err
+	}
+	if series.Until != nil || series.Count != nil {
+		// Truncated or split since it was listed
+		return nil
+	}
+
+	var template auth.Event
+	err = scanEvent(tx.QueryRow(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE series_id = $1 AND deleted_at IS NULL AND status IN ($2, $3)
+		ORDER BY start_time DESC
+		LIMIT 1`,
+		series.ID, StatusPending, StatusConfirmed), &template)
+	if err == sql.ErrNoRows {
+		// Nothing left to repeat
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rule, err := recurrence.Parse(series.RRule)
+	if err != nil {
+		return err
+	}
+
+	// Expand from the last generated occurrence in the series timezone; it comes first and is
+	// already stored
+	loc, err := timezone.ForProvider(tx, series.CreatedBy)
+	if err != nil {
+		return err
+	}
+	starts, err := rule.Expand(series.MaterializedUntil.In(loc), horizon)
+	if err != nil {
+		return err
+	}
+	if len(starts) < 2 {
+		return nil
+	}
+	starts = starts[1:]
+
+	checker := conflicts.NewConflictChecker(db).InTx(tx)
+	if template.AppointmentTypeID != nil {
+		appointmentType, err := appointmenttypes.Load(tx, *template.AppointmentTypeID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if appointmentType != nil {
+			checker.WithBuffers(appointmentType.BufferBeforeMinutes, appointmentType.BufferAfterMinutes)
+			if appointmentType.ScheduleID != nil {
+				checker.WithSchedule(*appointmentType.ScheduleID)
+			}
+		}
+	}
+
+	eventQuery := `
+		INSERT INTO events (id, title, description, start_time, end_time, event_type, status,
+		                   created_by, patient_id, appointment_type_id, series_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + eventColumns
+
+	actor := auditlog.Actor{UserID: series.CreatedBy, Role: systemRole}
+	duration := series.EndTime.Sub(series.StartTime)
+	now := time.Now().UTC()
+	for _, start := range starts {
+		end := start.Add(duration)
+
+		if template.EventType == "appointment" {
+			result, err := checker.CheckTimeSlotAvailability(series.CreatedBy, start, end)
+			if err != nil {
+				return err
+			}
+			if result.HasConflict {
+				message := fmt.Sprintf(
+					"The recurring appointment %q on %s was not added because the time is no longer available.",
+					template.Title, notify.FormatTime(tx, series.CreatedBy, start),
+				)
+				if err := notify.Send(tx, series.CreatedBy, notify.KindSeriesConflict, "", message); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		var event auth.Event
+		err := scanEvent(tx.QueryRow(
+			eventQuery,
+			uuid.New().String(), template.Title, template.Description, start, end,
+			template.EventType, template.Status, series.CreatedBy, template.PatientID, template.AppointmentTypeID,
+			series.ID, now, now,
+		), &event)
+		if err != nil {
+			return err
+		}
+		if err := auditlog.Record(tx, actor, auditlog.ActionCreate, auditlog.EntityEvent, event.ID, event.CreatedBy, nil, event); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE event_series SET materialized_until = $1, updated_at = $2 WHERE id = $3`, starts[len(starts)-1], now, series.ID)
+	if err != nil {
+		return err
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
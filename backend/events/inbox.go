@@ -0,0 +1,421 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/notify"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetInbox returns the provider's appointment inbox: pending requests awaiting a decision,
+// today's agenda (in the provider's timezone) and the pending or confirmed appointments of
+// the following days (default 7, max 62)
+func (eh *EventsHandler) GetInbox(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	if days > 62 {
+		days = 62
+	}
+
+	loc, err := timezone.ForProvider(eh.db, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load provider timezone", "details": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	todayStart, todayEnd := timezone.StartOfDay(timezone.Date(now, loc), loc)
+	upcomingEnd := todayEnd.AddDate(0, 0, days)
+
+	pending, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status = $2 AND end_time > $3 AND deleted_at IS NULL
+		ORDER BY start_time ASC`,
+		userCtx.UserID, StatusPending, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending requests"})
+		return
+	}
+
+	today, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE created_by = $1 AND status != $2 AND start_time < $4 AND end_time > $3 AND deleted_at IS NULL
+		ORDER BY start_time ASC`,
+		userCtx.UserID, StatusCancelled, todayStart, todayEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch today's agenda"})
+		return
+	}
+
+	upcoming, err := eh.queryEvents(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE created_by = $1 AND event_type = 'appointment' AND status IN ($2, $3)
+		AND start_time >= $4 AND start_time < $5 AND deleted_at IS NULL
+		ORDER BY start_time ASC`,
+		userCtx.UserID, StatusPending, StatusConfirmed, todayEnd, upcomingEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upcoming appointments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending":  pending,
+		"today":    today,
+		"upcoming": upcoming,
+		"timezone": loc.String(),
+	})
+}
+
+// ApproveAppointment confirms a pending request and notifies the patient
+func (eh *EventsHandler) ApproveAppointment(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	eh.decideRequest(c, userCtx, StatusConfirmed, nil)
+}
+
+// DeclineAppointment cancels a pending request, with an optional reason, and notifies the patient.
+// Declining is the provider's decision, so the cancellation policy does not apply.
+func (eh *EventsHandler) DeclineAppointment(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req auth.DeclineAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	eh.decideRequest(c, userCtx, StatusCancelled, req.Reason)
+}
+
+// decideRequest moves the provider's pending request in the :id path parameter to status
+// (confirmed or cancelled) and notifies the patient
+func (eh *EventsHandler) decideRequest(c *gin.Context, userCtx *auth.UserContext, status string, reason *string) {
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the event row, as every booking path does; a decline
+	// offers the freed time to the waitlist
+	if err := conflicts.LockProvider(tx, userCtx.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	event, ok := eh.lockPendingRequest(c, tx, userCtx, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	updated, err := applyTransition(tx, userCtx, event, status, reason, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event status"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityEvent, updated.ID, updated.CreatedBy, event, updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	kind := notify.KindAppointmentApproved
+	message := fmt.Sprintf("Your appointment request for %s has been confirmed", notify.FormatTime(tx, *updated.PatientID, updated.StartTime))
+	if status == StatusCancelled {
+		kind = notify.KindAppointmentDeclined
+		message = fmt.Sprintf("Your appointment request for %s has been declined", notify.FormatTime(tx, *updated.PatientID, updated.StartTime))
+		if reason != nil && *reason != "" {
+			message += ": " + *reason
+		}
+	}
+	if err = notify.Send(tx, *updated.PatientID, kind, updated.ID, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify patient"})
+		return
+	}
+
+	if status == StatusCancelled && !eh.offerFreedTime(c, tx, updated) {
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": updated})
+}
+
+// ProposeAppointmentTime proposes another start time for a pending request and notifies the
+// patient. The request stays pending until the patient accepts the proposal or cancels; the
+// proposed time must be free and within the provider's availability and booking policy.
+func (eh *EventsHandler) ProposeAppointmentTime(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req auth.ProposeTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if !req.StartTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed time must be in the future"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Serialize bookings for this provider before checking the proposed time
+	if err := conflicts.LockProvider(tx, userCtx.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	event, ok := eh.lockPendingRequest(c, tx, userCtx, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	proposedStart := req.StartTime.UTC()
+	proposedEnd := proposedStart.Add(event.EndTime.Sub(event.StartTime))
+	if !eh.checkProposedTime(c, tx, userCtx, event, proposedStart, proposedEnd) {
+		return
+	}
+
+	var updated auth.Event
+	err = scanEvent(tx.QueryRow(`
+		UPDATE events SET proposed_start_time = $2, proposed_end_time = $3, updated_at = $4
+		WHERE id = $1
+		RETURNING `+eventColumns,
+		event.ID, proposedStart, proposedEnd, time.Now().UTC()), &updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to propose time"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityEvent, updated.ID, updated.CreatedBy, event, updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	message := fmt.Sprintf("Your provider proposed moving your appointment request to %s", notify.FormatTime(tx, *updated.PatientID, proposedStart))
+	if req.Message != nil && *req.Message != "" {
+		message += ": " + *req.Message
+	}
+	if err = notify.Send(tx, *updated.PatientID, notify.KindTimeProposed, updated.ID, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify patient"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": updated})
+}
+
+// AcceptProposedTime lets the patient accept the time their provider proposed for a pending
+// request: the appointment moves to that time and is confirmed. To refuse it, the patient
+// cancels the appointment instead.
+func (eh *EventsHandler) AcceptProposedTime(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	eventID := c.Param("id")
+
+	// Find the provider first so their calendar can be locked before the event row
+	var providerID string
+	err := eh.db.QueryRow(`SELECT created_by FROM events WHERE id = $1 AND patient_id = $2 AND deleted_at IS NULL`, eventID, userCtx.UserID).Scan(&providerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	var event auth.Event
+	err = scanEvent(tx.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1 AND patient_id = $2 AND deleted_at IS NULL FOR UPDATE`, eventID, userCtx.UserID), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment"})
+		return
+	}
+
+	if event.Status != StatusPending || event.ProposedStartTime == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "No time has been proposed for this appointment"})
+		return
+	}
+	if !event.ProposedStartTime.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The proposed time has already passed"})
+		return
+	}
+
+	// The proposed time may have been booked since it was proposed
+	if !eh.checkProposedTime(c, tx, userCtx, &event, *event.ProposedStartTime, *event.ProposedEndTime) {
+		return
+	}
+
+	var updated auth.Event
+	err = scanEvent(tx.QueryRow(`
+		UPDATE events
+		SET start_time = proposed_start_time, end_time = proposed_end_time, status = $2,
+		    proposed_start_time = NULL, proposed_end_time = NULL, updated_at = $3
+		WHERE id = $1
+		RETURNING `+eventColumns,
+		event.ID, StatusConfirmed, time.Now().UTC()), &updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept proposed time"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityEvent, updated.ID, updated.CreatedBy, event, updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	message := fmt.Sprintf("Your proposed time of %s was accepted", notify.FormatTime(tx, updated.CreatedBy, updated.StartTime))
+	if err = notify.Send(tx, updated.CreatedBy, notify.KindProposalAccepted, updated.ID, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify provider"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": updated})
+}
+
+// lockPendingRequest loads and row-locks one of the provider's pending appointment requests.
+// Writes the error response and returns false if it is missing or no longer pending.
+func (eh *EventsHandler) lockPendingRequest(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, eventID string) (*auth.Event, bool) {
+	var event auth.Event
+	err := scanEvent(tx.QueryRow(`
+		SELECT `+eventColumns+`
+		FROM events
+		WHERE id = $1 AND created_by = $2 AND event_type = 'appointment' AND deleted_at IS NULL
+		FOR UPDATE`,
+		eventID, userCtx.UserID), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment"})
+		return nil, false
+	}
+
+	if event.Status != StatusPending || event.PatientID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only pending requests can be answered; this appointment is %s", event.Status)})
+		return nil, false
+	}
+	return &event, true
+}
+
+// checkProposedTime checks the appointment could be moved to the proposed time.
+// Writes the error response and returns false otherwise.
+func (eh *EventsHandler) checkProposedTime(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, event *auth.Event, start, end time.Time) bool {
+	appointmentType, err := eh.eventAppointmentType(event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+		return false
+	}
+
+	conflictResult, err := eh.bookingChecker(tx, userCtx, appointmentType).ExcludeEvents(event.ID).CheckTimeSlotAvailability(event.CreatedBy, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return false
+	}
+	if conflictResult.HasConflict {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                 "Time slot not available",
+			"conflict_type":         conflictResult.ConflictType,
+			"message":               conflictResult.Message,
+			"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+		})
+		return false
+	}
+	return true
+}
+
+// queryEvents runs a query selecting eventColumns and returns every row (never nil)
+func (eh *EventsHandler) queryEvents(query string, args ...interface{}) ([]auth.Event, error) {
+	rows, err := eh.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []auth.Event{}
+	for rows.Next() {
+		var event auth.Event
+		if err := scanEvent(rows, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/availability"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BookAppointment books the calling patient into one of a provider's open slots. The slot must be
+// one the slots engine publishes for the appointment type with the default step, and must still
+// satisfy the provider's booking policy. Patients cannot choose the length or step themselves, so
+// they can only book the provider's appointment types. A slot the patient holds is booked with its
+// hold_id. The appointment is created pending confirmation.
+func (eh *EventsHandler) BookAppointment(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var req auth.BookAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	// The appointment type sets the title, length, buffers and schedule
+	appointmentType, ok := eh.resolveAppointmentType(c, req.AppointmentTypeID, req.ProviderID)
+	if !ok {
+		return
+	}
+
+	startTime := req.StartTime.UTC()
+	endTime := startTime.Add(time.Duration(appointmentType.DurationMinutes) * time.Minute)
+
+	// Only slots the provider publishes can be booked
+	offered, err := availability.OffersSlot(eh.db, req.ProviderID, appointmentType, startTime, endTime, 0, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return
+	}
+	if !offered {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Time slot not available",
+			"message": "Choose one of the provider's open slots from /api/v1/slots",
+		})
+		return
+	}
+
+	// Start transaction so the conflict check and the insert happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Serialize bookings for this provider, then re-check the slot against the booking policy
+	// and any event booked since the slots were listed
+	if err := conflicts.LockProvider(tx, req.ProviderID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	if req.HoldID != nil && *req.HoldID != "" {
+		if !eh.consumeHold(c, tx, userCtx, *req.HoldID, req.ProviderID, startTime, endTime) {
+			return
+		}
+	}
+
+	conflictResult, err := eh.bookingChecker(tx, userCtx, appointmentType).CheckTimeSlotAvailability(req.ProviderID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return
+	}
+	if conflictResult.HasConflict {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                 "Time slot not available",
+			"conflict_type":         conflictResult.ConflictType,
+			"message":               conflictResult.Message,
+			"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+		})
+		return
+	}
+
+	event, err := insertRequest(tx, req.ProviderID, userCtx.UserID, &appointmentType.ID, appointmentType.Name, req.Description, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionCreate, auditlog.EntityEvent, event.ID, event.CreatedBy, nil, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"event": event})
+}
+
+// insertRequest books a patient's appointment request with the provider, pending confirmation
+func insertRequest(tx *sql.Tx, providerID, patientID string, appointmentTypeID *string, title string, description *string, start, end time.Time) (*auth.Event, error) {
+	query := `
+		INSERT INTO events (id, title, description, start_time, end_time, event_type, status,
+		                   created_by, patient_id, appointment_type_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'appointment', $6, $7, $8, $9, $10, $10)
+		RETURNING ` + eventColumns
+
+	var event auth.Event
+	err := scanEvent(tx.QueryRow(
+		query,
+		uuid.New().String(), title, description, start, end, StatusPending,
+		providerID, patientID, appointmentTypeID, time.Now().UTC(),
+	), &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
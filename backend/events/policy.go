@@ -0,0 +1,84 @@
+package events
+
+import (
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// policyOverride reports whether the caller asked to bypass the provider's cancellation and
+// reschedule policy with ?override_policy=true. Only admins may; for anyone else it writes
+// the error response and returns false as its second value.
+func policyOverride(c *gin.Context, userCtx *auth.UserContext) (bool, bool) {
+	if c.Query("override_policy") != "true" {
+		return false, true
+	}
+	if userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can override the booking policy"})
+		return false, false
+	}
+	return true, true
+}
+
+// isActiveAppointment reports whether the event is an appointment the policy still governs
+func isActiveAppointment(event *auth.Event) bool {
+	return event.EventType == "appointment" && (event.Status == StatusPending || event.Status == StatusConfirmed)
+}
+
+// checkCancellationPolicy applies the provider's policy to cancelling (or deleting) an
+// appointment. It returns whether the cancellation is late; on a violation that is not
+// overridden it writes the error response and returns false as its second value.
+func checkCancellationPolicy(c *gin.Context, db policy.Queryer, event *auth.Event, override bool) (bool, bool) {
+	p, err := policy.ForProvider(db, event.CreatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cancellation policy", "details": err.Error()})
+		return false, false
+	}
+
+	violation, late := p.CheckCancellation(event.StartTime, time.Now().UTC())
+	if violation != nil && !override {
+		respondPolicyViolation(c, p, violation)
+		return false, false
+	}
+	return late, true
+}
+
+// checkReschedulePolicy applies the provider's policy to moving the given appointments.
+// On a violation that is not overridden it writes the error response and returns false.
+func checkReschedulePolicy(c *gin.Context, db policy.Queryer, providerID string, events []auth.Event, override bool) bool {
+	if override {
+		return true
+	}
+
+	p, err := policy.ForProvider(db, providerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reschedule policy", "details": err.Error()})
+		return false
+	}
+
+	now := time.Now().UTC()
+	for i := range events {
+		if !isActiveAppointment(&events[i]) {
+			continue
+		}
+		if violation := p.CheckReschedule(events[i].StartTime, now, events[i].RescheduleCount); violation != nil {
+			respondPolicyViolation(c, p, violation)
+			return false
+		}
+	}
+	return true
+}
+
+// respondPolicyViolation writes the 422 response explaining which rule was broken
+func respondPolicyViolation(c *gin.Context, p *policy.Policy, violation *policy.Violation) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":   "Booking policy violation",
+		"rule":    violation.Rule,
+		"message": violation.Message,
+		"policy":  p,
+	})
+}
@@ -0,0 +1,692 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/recurrence"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// occurrence is the time range of a single event in a recurring series
+type occurrence struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// expandRecurrence resolves a recurrence request into its rule and concrete occurrences.
+// Explicit until/count values take precedence over the bounds in the RRULE itself;
+// open-ended series are expanded up to recurrence.DefaultHorizon and extended later by
+// ExtendSeries. Occurrences keep the first one's wall-clock time in loc, the series timezone.
+func expandRecurrence(req *auth.RecurrenceRequest, startTime, endTime time.Time, loc *time.Location) (*recurrence.Rule, []occurrence, error) {
+	if req.Until != nil && req.Count != nil {
+		return nil, nil, fmt.Errorf("specify either until or count, not both")
+	}
+
+	rule, err := recurrence.Parse(req.RRule)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Until != nil {
+		until := *req.Until
+		rule.Until = &until
+		rule.Count = 0
+	}
+	if req.Count != nil {
+		rule.Count = *req.Count
+		rule.Until = nil
+	}
+
+	var horizon time.Time
+	if rule.Count == 0 && rule.Until == nil {
+		horizon = startTime.Add(recurrence.DefaultHorizon)
+	}
+
+	starts, err := rule.Expand(startTime.In(loc), horizon)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(starts) == 0 {
+		return nil, nil, fmt.Errorf("recurrence rule produces no occurrences")
+	}
+
+	duration := endTime.Sub(startTime)
+	occurrences := make([]occurrence, 0, len(starts))
+	for _, start := range starts {
+		occurrences = append(occurrences, occurrence{StartTime: start, EndTime: start.Add(duration)})
+	}
+
+	return rule, occurrences, nil
+}
+
+// checkOccurrences locks the provider's calendar within tx, runs the conflict checker
+// against every occurrence and returns the ones that clash. Events in excludeIDs are
+// the ones being moved and never conflict with their own new times.
+func (eh *EventsHandler) checkOccurrences(tx *sql.Tx, userCtx *auth.UserContext, providerID string, appointmentType *appointmenttypes.AppointmentType, occurrences []occurrence, excludeIDs []string) ([]gin.H, error) {
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		return nil, err
+	}
+
+	conflictChecker := eh.bookingChecker(tx, userCtx, appointmentType).ExcludeEvents(excludeIDs...)
+
+	clashes := []gin.H{}
+	for _, occ := range occurrences {
+		result, err := conflictChecker.CheckTimeSlotAvailability(providerID, occ.StartTime, occ.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		if result.HasConflict {
+			clashes = append(clashes, gin.H{
+				"start_time":            occ.StartTime,
+				"end_time":              occ.EndTime,
+				"conflict_type":         result.ConflictType,
+				"message":               result.Message,
+				"conflicting_event_ids": result.ConflictingEventIDs,
+			})
+		}
+	}
+
+	return clashes, nil
+}
+
+// createSeries creates a recurring series in createdBy's calendar and stores every occurrence as an event.
+// The whole series is rejected if any appointment occurrence conflicts.
+func (eh *EventsHandler) createSeries(c *gin.Context, userCtx *auth.UserContext, req *auth.CreateEventRequest, createdBy string, appointmentType *appointmenttypes.AppointmentType) {
+	// Occurrences repeat at the same local time in the provider's timezone
+	loc, err := timezone.ForProvider(eh.db, createdBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load provider timezone"})
+		return
+	}
+
+	rule, occurrences, err := expandRecurrence(req.Recurrence, req.StartTime, req.EndTime, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurrence", "details": err.Error()})
+		return
+	}
+
+	// Start transaction so the conflict checks and the inserts happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Only check conflicts for appointments (not for blocks)
+	if req.EventType == "appointment" {
+		clashes, err := eh.checkOccurrences(tx, userCtx, createdBy, appointmentType, occurrences, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to check availability",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if len(clashes) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     "One or more occurrences are not available",
+				"conflicts": clashes,
+			})
+			return
+		}
+	}
+
+	var count *int
+	if rule.Count > 0 {
+		count = &rule.Count
+	}
+
+	seriesQuery := `
+		INSERT INTO event_series (id, rrule, start_time, end_time, until, count, materialized_until, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + seriesColumns
+
+	var series auth.EventSeries
+	now := time.Now().UTC()
+	err = scanSeries(tx.QueryRow(
+		seriesQuery,
+		uuid.New().String(), rule.String(), req.StartTime, req.EndTime,
+		rule.Until, count, occurrences[len(occurrences)-1].StartTime, createdBy, now, now,
+	), &series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event series", "details": err.Error()})
+		return
+	}
+
+	actor := userCtx.AuditActor(c)
+	if err = auditlog.Record(tx, actor, auditlog.ActionCreate, auditlog.EntityEventSeries, series.ID, series.CreatedBy, nil, series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	eventQuery := `
+		INSERT INTO events (id, title, description, start_time, end_time, event_type, status,
+		                   created_by, patient_id, appointment_type_id, series_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + eventColumns
+
+	events := make([]auth.Event, 0, len(occurrences))
+	for _, occ := range occurrences {
+		var event auth.Event
+		err := scanEvent(tx.QueryRow(
+			eventQuery,
+			uuid.New().String(), req.Title, req.Description, occ.StartTime, occ.EndTime,
+			req.EventType, req.Status, createdBy, req.PatientID, req.AppointmentTypeID, series.ID,
+			now, now,
+		), &event)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+			return
+		}
+		if err := auditlog.Record(tx, actor, auditlog.ActionCreate, auditlog.EntityEvent, event.ID, event.CreatedBy, nil, event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+			return
+		}
+		events = append(events, event)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"series": series,
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// updateFollowing applies an update to an occurrence and every later occurrence of its series.
+// The edited occurrences are split off into a new series and the original series is truncated.
+func (eh *EventsHandler) updateFollowing(c *gin.Context, userCtx *auth.UserContext, existingEvent *auth.Event, req *auth.UpdateEventRequest, override bool) {
+	// Time changes are applied as a shift of every occurrence plus a new duration
+	newStart := existingEvent.StartTime
+	if req.StartTime != nil {
+		newStart = *req.StartTime
+	}
+
+	newEnd := existingEvent.EndTime.Add(newStart.Sub(existingEvent.StartTime))
+	if req.EndTime != nil {
+		newEnd = *req.EndTime
+	}
+	duration := newEnd.Sub(newStart)
+	if duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
+		return
+	}
+
+	// Occurrences move by whole days to the new local start time in the provider's timezone,
+	// so a series moved from 09:00 to 10:00 stays at 10:00 across DST changes
+	loc, err := timezone.ForProvider(eh.db, existingEvent.CreatedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load provider timezone"})
+		return
+	}
+	dayShift := daysBetween(existingEvent.StartTime.In(loc), newStart.In(loc))
+	clock := newStart.In(loc).Format("15:04:05")
+
+	timesChanged := req.StartTime != nil || req.EndTime != nil
+
+	eventType := existingEvent.EventType
+	if req.EventType != nil {
+		eventType = *req.EventType
+	}
+
+	// Start transaction so the conflict checks and the updates happen atomically
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Read the occurrences under the calendar lock so none are added or moved meanwhile
+	if err := conflicts.LockProvider(tx, existingEvent.CreatedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	following, err := getFollowingOccurrences(tx, *existingEvent.SeriesID, existingEvent.StartTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series occurrences"})
+		return
+	}
+	if len(following) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	shifted := make([]occurrence, 0, len(following))
+	followingIDs := make([]string, 0, len(following))
+	for _, event := range following {
+		start, err := shiftWallClock(event.StartTime, dayShift, clock, loc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift series occurrences"})
+			return
+		}
+		shifted = append(shifted, occurrence{StartTime: start, EndTime: start.Add(duration)})
+		followingIDs = append(followingIDs, event.ID)
+	}
+
+	// Every moved appointment must respect the provider's reschedule policy
+	rescheduled := timesChanged && (!newStart.Equal(existingEvent.StartTime) || !newEnd.Equal(existingEvent.EndTime))
+	if rescheduled && !checkReschedulePolicy(c, tx, existingEvent.CreatedBy, following, override) {
+		return
+	}
+
+	// Re-check availability for every occurrence that moves or becomes an appointment.
+	// Cancelled occurrences take up no time and are left out.
+	changed := make([]occurrence, 0, len(following))
+	for i, event := range following {
+		if event.Status == StatusCancelled {
+			continue
+		}
+		if timesChanged {
+			changed = append(changed, shifted[i])
+		} else if event.EventType != eventType {
+			changed = append(changed, occurrence{StartTime: event.StartTime, EndTime: event.EndTime})
+		}
+	}
+
+	if eventType == "appointment" && len(changed) > 0 {
+		appointmentType, err := eh.eventAppointmentType(existingEvent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
+
+		clashes, err := eh.checkOccurrences(tx, userCtx, existingEvent.CreatedBy, appointmentType, changed, followingIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to check availability",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if len(clashes) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     "One or more occurrences are not available",
+				"conflicts": clashes,
+			})
+			return
+		}
+	}
+
+	// Build the shared part of the update; times are set per occurrence
+	updateFields := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Title != nil {
+		updateFields = append(updateFields, fmt.Sprintf("title = $%d", argIndex))
+		args = append(args, *req.Title)
+		argIndex++
+	}
+
+	if req.Description != nil {
+		updateFields = append(updateFields, fmt.Sprintf("description = $%d", argIndex))
+		args = append(args, req.Description)
+		argIndex++
+	}
+
+	if req.EventType != nil {
+		updateFields = append(updateFields, fmt.Sprintf("event_type = $%d", argIndex))
+		args = append(args, *req.EventType)
+		argIndex++
+	}
+
+	if req.PatientID != nil {
+		updateFields = append(updateFields, fmt.Sprintf("patient_id = $%d", argIndex))
+		args = append(args, req.PatientID)
+		argIndex++
+	}
+
+	if len(updateFields) == 0 && !timesChanged {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	var original auth.EventSeries
+	err = scanSeries(tx.QueryRow(`SELECT `+seriesColumns+` FROM event_series WHERE id = $1 FOR UPDATE`, *existingEvent.SeriesID), &original)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event series"})
+		return
+	}
+
+	// The split-off series keeps the original pattern, moved to the new weekday if needed
+	rule, err := recurrence.Parse(original.RRule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored recurrence rule is invalid"})
+		return
+	}
+	rule.ShiftDays(dayShift)
+	rule.Count = 0
+	rule.Until = original.Until
+
+	var count *int
+	if original.Count != nil {
+		remaining := len(following)
+		rule.Count = remaining
+		count = &remaining
+	}
+
+	// Open-ended series are extended from the last generated occurrence, which moves with the rest
+	materializedUntil := original.MaterializedUntil
+	if timesChanged {
+		if materializedUntil, err = shiftWallClock(original.MaterializedUntil, dayShift, clock, loc); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift series occurrences"})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	var series auth.EventSeries
+	err = scanSeries(tx.QueryRow(`
+		INSERT INTO event_series (id, rrule, start_time, end_time, until, count, materialized_until, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+seriesColumns,
+		uuid.New().String(), rule.String(), newStart, newStart.Add(duration),
+		original.Until, count, materializedUntil, original.CreatedBy, now, now,
+	), &series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event series", "details": err.Error()})
+		return
+	}
+
+	actor := userCtx.AuditActor(c)
+	if err = auditlog.Record(tx, actor, auditlog.ActionCreate, auditlog.EntityEventSeries, series.ID, series.CreatedBy, nil, series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	updateFields = append(updateFields,
+		fmt.Sprintf("start_time = $%d", argIndex),
+		fmt.Sprintf("end_time = $%d", argIndex+1),
+		fmt.Sprintf("series_id = $%d", argIndex+2),
+		fmt.Sprintf("updated_at = $%d", argIndex+3),
+	)
+	if timesChanged {
+		updateFields = append(updateFields, "is_exception = false")
+	}
+	if rescheduled {
+		updateFields = append(updateFields, "reschedule_count = reschedule_count + CASE WHEN event_type = 'appointment' AND status IN ('pending', 'confirmed') THEN 1 ELSE 0 END")
+	}
+	updateQuery := fmt.Sprintf(`
+		UPDATE events
+		SET %s
+		WHERE id = $%d
+		RETURNING %s`,
+		strings.Join(updateFields, ", "),
+		argIndex+4,
+		eventColumns)
+
+	updated := make([]auth.Event, 0, len(following))
+	for i, event := range following {
+		start, end := event.StartTime, event.EndTime
+		if timesChanged {
+			start, end = shifted[i].StartTime, shifted[i].EndTime
+		}
+
+		rowArgs := append(append([]interface{}{}, args...), start, end, series.ID, now, event.ID)
+		var updatedEvent auth.Event
+		if err := scanEvent(tx.QueryRow(updateQuery, rowArgs...), &updatedEvent); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+			return
+		}
+		if err := auditlog.Record(tx, actor, auditlog.ActionUpdate, auditlog.EntityEvent, event.ID, updatedEvent.CreatedBy, event, updatedEvent); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+			return
+		}
+		updated = append(updated, updatedEvent)
+	}
+
+	// End the original series just before the edited occurrence
+	if err := truncateSeries(tx, original.ID, existingEvent.StartTime, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event series"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series": series,
+		"events": updated,
+		"count":  len(updated),
+	})
+}
+
+// deleteFollowing soft deletes an occurrence and every later occurrence of its series,
+// cancelling the active appointments among them first
+func (eh *EventsHandler) deleteFollowing(c *gin.Context, userCtx *auth.UserContext, eventID string, override bool) {
+	existingEvent, err := eh.getAccessibleEvent(userCtx, eventID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	if existingEvent.SeriesID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event is not part of a recurring series"})
+		return
+	}
+
+	// Only the first occurrence can fall inside the cancellation notice period
+	late := false
+	if isActiveAppointment(existingEvent) {
+		var ok bool
+		if late, ok = checkCancellationPolicy(c, eh.db, existingEvent, override); !ok {
+			return
+		}
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the occurrences, as every change that frees time does
+	if err := conflicts.LockProvider(tx, existingEvent.CreatedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	following, err := getFollowingOccurrences(tx, *existingEvent.SeriesID, existingEvent.StartTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series occurrences"})
+		return
+	}
+
+	// Keep each occurrence's status for RestoreEvent before the active ones are cancelled
+	_, err = tx.Exec(`
+		UPDATE events SET status_before_delete = status
+		WHERE series_id = $1 AND start_time >= $2 AND deleted_at IS NULL`,
+		*existingEvent.SeriesID, existingEvent.StartTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete events"})
+		return
+	}
+
+	// Active appointments are cancelled before they are deleted; only the first occurrence
+	// can be a late cancellation
+	actor := userCtx.AuditActor(c)
+	reason := deletionReason(c)
+	var freed []auth.Event
+	for i := range following {
+		event := &following[i]
+		if event.Status != StatusCancelled {
+			freed = append(freed, *event)
+		}
+		if isActiveAppointment(event) && !cancelForDeletion(c, tx, userCtx, actor, event, reason, late && event.ID == existingEvent.ID) {
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	rows, err := tx.Query(`
+		UPDATE events
+		SET deleted_at = $3, updated_at = $3
+		WHERE series_id = $1 AND start_time >= $2 AND deleted_at IS NULL
+		RETURNING `+eventColumns,
+		*existingEvent.SeriesID, existingEvent.StartTime, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete events"})
+		return
+	}
+
+	var deleted []auth.Event
+	for rows.Next() {
+		var event auth.Event
+		if err := scanEvent(rows, &event); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+			return
+		}
+		deleted = append(deleted, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+
+	for _, event := range deleted {
+		before := event
+		before.DeletedAt = nil
+		if err := auditlog.Record(tx, actor, auditlog.ActionDelete, auditlog.EntityEvent, event.ID, event.CreatedBy, before, event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+			return
+		}
+	}
+
+	// The deleted occurrences' time is free for the waitlist
+	for i := range freed {
+		if !eh.offerFreedTime(c, tx, &freed[i]) {
+			return
+		}
+	}
+
+	// End the series just before the first deleted occurrence
+	if err := truncateSeries(tx, *existingEvent.SeriesID, existingEvent.StartTime, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event series"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Events deleted successfully",
+		"deleted": len(deleted),
+	})
+}
+
+// getFollowingOccurrences loads and locks the occurrences of a series starting at or after from
+func getFollowingOccurrences(tx *sql.Tx, seriesID string, from time.Time) ([]auth.Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events
+		WHERE series_id = $1 AND start_time >= $2 AND deleted_at IS NULL
+		ORDER BY start_time ASC
+		FOR UPDATE`
+
+	rows, err := tx.Query(query, seriesID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []auth.Event
+	for rows.Next() {
+		var event auth.Event
+		if err := scanEvent(rows, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// seriesColumns lists the columns selected for every event series, in the order scanSeries expects
+const seriesColumns = `id, rrule, start_time, end_time, until, count, materialized_until, created_by, created_at, updated_at`
+
+// scanSeries scans a row selected with seriesColumns into series
+func scanSeries(row rowScanner, series *auth.EventSeries) error {
+	return row.Scan(
+		&series.ID, &series.RRule, &series.StartTime, &series.EndTime,
+		&series.Until, &series.Count, &series.MaterializedUntil, &series.CreatedBy, &series.CreatedAt, &series.UpdatedAt,
+	)
+}
+
+// truncateSeries ends a series just before the occurrence starting at before,
+// and drops the series entirely once none of its occurrences remain. Soft deleted
+// occurrences still count, so a restored occurrence keeps its series.
+func truncateSeries(tx *sql.Tx, seriesID string, before time.Time, now time.Time) error {
+	var rrule string
+	if err := tx.QueryRow(`SELECT rrule FROM event_series WHERE id = $1`, seriesID).Scan(&rrule); err != nil {
+		return err
+	}
+
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		return err
+	}
+	until := before.Add(-time.Second)
+	rule.Until = &until
+	rule.Count = 0
+
+	_, err = tx.Exec(`
+		UPDATE event_series SET rrule = $1, until = $2, count = NULL, updated_at = $3 WHERE id = $4`,
+		rule.String(), until, now, seriesID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM event_series
+		WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM events WHERE series_id = $1)`,
+		seriesID)
+	return err
+}
+
+// shiftWallClock moves t by days calendar days in loc and sets its local time of day to clock
+// ("15:04:05"), so the result keeps that wall-clock time whatever the DST offset on the new day
+func shiftWallClock(t time.Time, days int, clock string, loc *time.Location) (time.Time, error) {
+	return timezone.WallClock(timezone.Date(t, loc).AddDate(0, 0, days), clock, loc)
+}
+
+// daysBetween returns the number of calendar days from a to b, ignoring clock time
+func daysBetween(a, b time.Time) int {
+	dayA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dayB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dayB.Sub(dayA).Hours() / 24)
+}
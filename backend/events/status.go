@@ -0,0 +1,286 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Appointment statuses. An appointment moves pending -> confirmed -> checked_in -> completed,
+// or from confirmed to no_show; it can be cancelled until the patient checks in.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusCheckedIn = "checked_in"
+	StatusCompleted = "completed"
+	StatusNoShow    = "no_show"
+	StatusCancelled = "cancelled"
+)
+
+// transition is a permitted status change and the roles allowed to make it
+type transition struct {
+	action string
+	from   []string
+	to     string
+	roles  []string
+}
+
+// transitions is the appointment lifecycle. Patients may only cancel.
+var transitions = []transition{
+	{action: "confirm", from: []string{StatusPending}, to: StatusConfirmed, roles: []string{"provider", "admin"}},
+	{action: "check-in", from: []string{StatusConfirmed}, to: StatusCheckedIn, roles: []string{"provider", "admin"}},
+	{action: "complete", from: []string{StatusCheckedIn}, to: StatusCompleted, roles: []string{"provider", "admin"}},
+	{action: "no-show", from: []string{StatusConfirmed}, to: StatusNoShow, roles: []string{"provider", "admin"}},
+	{action: "cancel", from: []string{StatusPending, StatusConfirmed}, to: StatusCancelled, roles: []string{"provider", "admin", "patient"}},
+}
+
+// findTransition returns the transition that moves an appointment into status
+func findTransition(to string) *transition {
+	for i := range transitions {
+		if transitions[i].to == to {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
+
+// findAction returns the transition performed by an endpoint action
+func findAction(action string) *transition {
+	for i := range transitions {
+		if transitions[i].action == action {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
+
+// allows reports whether the transition may start from status
+func (t *transition) allows(status string) bool {
+	for _, from := range t.from {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// permits reports whether the role may perform the transition
+func (t *transition) permits(role string) bool {
+	for _, allowed := range t.roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTransition checks a status change of an appointment by the caller, returning
+// the HTTP status and message to respond with when it is not allowed
+func validateTransition(userCtx *auth.UserContext, from, to string) (int, string) {
+	t := findTransition(to)
+	if t == nil {
+		return http.StatusBadRequest, fmt.Sprintf("Appointments cannot be moved back to %s", to)
+	}
+	if !t.permits(userCtx.UserRole) {
+		return http.StatusForbidden, fmt.Sprintf("Your role cannot %s appointments", t.action)
+	}
+	if !t.allows(from) {
+		return http.StatusConflict, fmt.Sprintf("Cannot %s an appointment that is %s", t.action, from)
+	}
+	return 0, ""
+}
+
+// ConfirmEvent confirms a pending appointment
+func (eh *EventsHandler) ConfirmEvent(c *gin.Context) {
+	eh.transitionEvent(c, "confirm", nil)
+}
+
+// CheckInEvent marks that the patient of a confirmed appointment has arrived
+func (eh *EventsHandler) CheckInEvent(c *gin.Context) {
+	eh.transitionEvent(c, "check-in", nil)
+}
+
+// CompleteEvent marks a checked-in appointment as completed
+func (eh *EventsHandler) CompleteEvent(c *gin.Context) {
+	eh.transitionEvent(c, "complete", nil)
+}
+
+// NoShowEvent marks a confirmed appointment as missed by the patient
+func (eh *EventsHandler) NoShowEvent(c *gin.Context) {
+	eh.transitionEvent(c, "no-show", nil)
+}
+
+// CancelEvent cancels a pending or confirmed appointment, recording the reason and who cancelled it
+func (eh *EventsHandler) CancelEvent(c *gin.Context) {
+	var req auth.CancelEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	eh.transitionEvent(c, "cancel", &req.Reason)
+}
+
+// transitionEvent applies a lifecycle action to the appointment in the :id path parameter
+func (eh *EventsHandler) transitionEvent(c *gin.Context, action string, reason *string) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	eventID := c.Param("id")
+
+	t := findAction(action)
+	if !t.permits(userCtx.UserRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Your role cannot %s appointments", action)})
+		return
+	}
+
+	override, ok := policyOverride(c, userCtx)
+	if !ok {
+		return
+	}
+
+	// Check the event exists and the user has access to it
+	existingEvent, err := eh.getAccessibleEvent(userCtx, eventID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the provider's calendar before the event row; a cancellation offers the freed time
+	// to the waitlist
+	if err := conflicts.LockProvider(tx, existingEvent.CreatedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return
+	}
+
+	// Re-read the status under a row lock so concurrent transitions apply one at a time
+	var event auth.Event
+	err = scanEvent(tx.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, eventID), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		return
+	}
+
+	if !t.allows(event.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot %s an appointment that is %s", action, event.Status)})
+		return
+	}
+
+	// Cancellations must respect the provider's notice period
+	late := false
+	if t.to == StatusCancelled && event.EventType == "appointment" {
+		if late, ok = checkCancellationPolicy(c, tx, &event, override); !ok {
+			return
+		}
+	}
+
+	updated, err := applyTransition(tx, userCtx, &event, t.to, reason, late)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event status"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionUpdate, auditlog.EntityEvent, eventID, updated.CreatedBy, event, updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// A cancelled appointment frees its time for the waitlist
+	if updated.Status == StatusCancelled && updated.EventType == "appointment" {
+		if !eh.offerFreedTime(c, tx, updated) {
+			return
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": updated})
+}
+
+// cancelForDeletion cancels an active appointment that is about to be deleted, so the deleted
+// row still records who cancelled it, when and why, and updates event in place. Writes the
+// error response and returns false if the caller's role cannot cancel or the update fails.
+func cancelForDeletion(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, actor auditlog.Actor, event *auth.Event, reason *string, late bool) bool {
+	if status, message := validateTransition(userCtx, event.Status, StatusCancelled); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return false
+	}
+
+	cancelled, err := applyTransition(tx, userCtx, event, StatusCancelled, reason, late)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel event"})
+		return false
+	}
+
+	if err := auditlog.Record(tx, actor, auditlog.ActionUpdate, auditlog.EntityEvent, event.ID, cancelled.CreatedBy, event, cancelled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return false
+	}
+
+	*event = *cancelled
+	return true
+}
+
+// deletionReason returns the optional cancellation reason given when deleting appointments
+func deletionReason(c *gin.Context) *string {
+	if reason := c.Query("reason"); reason != "" {
+		return &reason
+	}
+	return nil
+}
+
+// applyTransition writes an appointment's new status and drops any time proposed for it.
+// Cancellations also record the reason, the cancelling user and whether they cancelled late.
+func applyTransition(tx *sql.Tx, userCtx *auth.UserContext, event *auth.Event, status string, reason *string, late bool) (*auth.Event, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE events SET status = $1, updated_at = $2, proposed_start_time = NULL, proposed_end_time = NULL
+		WHERE id = $3
+		RETURNING ` + eventColumns
+	args := []interface{}{status, now, event.ID}
+
+	if status == StatusCancelled {
+		query = `
+			UPDATE events
+			SET status = $1, updated_at = $2, cancellation_reason = $4, cancelled_by = $5, cancelled_at = $2,
+			    late_cancellation = $6, proposed_start_time = NULL, proposed_end_time = NULL
+			WHERE id = $3
+			RETURNING ` + eventColumns
+		args = append(args, reason, userCtx.UserID, late)
+	}
+
+	var updated auth.Event
+	if err := scanEvent(tx.QueryRow(query, args...), &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"net/http"
+	"testing"
+
+	"emr-calendar-backend/auth"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		from, to string
+		want     int
+	}{
+		{"provider confirms pending", "provider", StatusPending, StatusConfirmed, 0},
+		{"admin checks in confirmed", "admin", StatusConfirmed, StatusCheckedIn, 0},
+		{"provider completes checked in", "provider", StatusCheckedIn, StatusCompleted, 0},
+		{"provider marks no-show", "provider", StatusConfirmed, StatusNoShow, 0},
+		{"patient cancels pending", "patient", StatusPending, StatusCancelled, 0},
+		{"patient cancels confirmed", "patient", StatusConfirmed, StatusCancelled, 0},
+		{"patient cannot confirm", "patient", StatusPending, StatusConfirmed, http.StatusForbidden},
+		{"no way back to pending", "provider", StatusConfirmed, StatusPending, http.StatusBadRequest},
+		{"unknown status", "admin", StatusPending, "archived", http.StatusBadRequest},
+		{"cannot complete without check-in", "provider", StatusConfirmed, StatusCompleted, http.StatusConflict},
+		{"cannot no-show a pending appointment", "provider", StatusPending, StatusNoShow, http.StatusConflict},
+		{"cannot cancel after check-in", "provider", StatusCheckedIn, StatusCancelled, http.StatusConflict},
+		{"cannot revive a cancelled appointment", "admin", StatusCancelled, StatusConfirmed, http.StatusConflict},
+		{"cannot revive a completed appointment", "admin", StatusCompleted, StatusCheckedIn, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userCtx := &auth.UserContext{UserRole: tt.role}
+			if got, message := validateTransition(userCtx, tt.from, tt.to); got != tt.want {
+				t.Errorf("validateTransition(%s -> %s) = %d %q, want %d", tt.from, tt.to, got, message, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAction(t *testing.T) {
+	for _, tr := range transitions {
+		if got := findAction(tr.action); got == nil || got.to != tr.to {
+			t.Errorf("findAction(%q) does not lead to %s", tr.action, tr.to)
+		}
+	}
+	if findAction("reopen") != nil {
+		t.Error("findAction found an unknown action")
+	}
+}
@@ -0,0 +1,462 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/auditlog"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/holds"
+	"emr-calendar-backend/lib/waitlist"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// JoinWaitlist adds the calling patient to a provider's waitlist for one of the provider's appointment
+// types, optionally with date, weekday and time-of-day preferences. When matching time frees up it is
+// offered to them in the order patients joined.
+func (eh *EventsHandler) JoinWaitlist(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if userCtx.UserRole != "patient" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only patients can join a waitlist"})
+		return
+	}
+
+	var req waitlist.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if req.EarliestDate != nil && req.LatestDate != nil && *req.LatestDate < *req.EarliestDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latest_date must not be before earliest_date"})
+		return
+	}
+	for _, t := range []*string{req.EarliestTime, req.LatestTime} {
+		if t != nil && !isClockTime(*t) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time format, use HH:MM"})
+			return
+		}
+	}
+	if req.EarliestTime != nil && req.LatestTime != nil && normalizeClock(*req.LatestTime) <= normalizeClock(*req.EarliestTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latest_time must be after earliest_time"})
+		return
+	}
+
+	// Only providers with a calendar have a waitlist
+	var isProvider bool
+	if err := eh.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND role = 'provider')`, req.ProviderID).Scan(&isProvider); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider"})
+		return
+	}
+	if !isProvider {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+		return
+	}
+
+	// The appointment type sets the slot length
+	appointmentType, ok := eh.resolveAppointmentType(c, req.AppointmentTypeID, req.ProviderID)
+	if !ok {
+		return
+	}
+
+	entry, err := waitlist.Create(eh.db, userCtx.UserID, &req, appointmentType.DurationMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"entry": entry})
+}
+
+// GetWaitlist lists waitlist entries in the order they joined, each with its open offer.
+// Patients see their own entries; providers and admins see the entries of providers in their
+// tenant (optionally one provider_id). Cancelled and booked entries are only listed with
+// status=cancelled or status=booked.
+func (eh *EventsHandler) GetWaitlist(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	var query string
+	var args []interface{}
+	if userCtx.IsStaff() {
+		scope, scopeArgs := userCtx.ProviderScope("provider_id", 1)
+		query = `SELECT ` + waitlist.EntryColumns + ` FROM waitlist_entries WHERE ` + scope
+		args = scopeArgs
+		if providerID := c.Query("provider_id"); providerID != "" {
+			query += fmt.Sprintf(" AND provider_id = $%d", len(args)+1)
+			args = append(args, providerID)
+		}
+	} else {
+		query = `SELECT ` + waitlist.EntryColumns + ` FROM waitlist_entries WHERE patient_id = $1`
+		args = []interface{}{userCtx.UserID}
+	}
+
+	if status := c.Query("status"); status != "" {
+		query += fmt.Sprintf(" AND status = $%d", len(args)+1)
+		args = append(args, status)
+	} else {
+		query += fmt.Sprintf(" AND status IN ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, waitlist.StatusWaiting, waitlist.StatusOffered)
+	}
+	query += " ORDER BY created_at ASC"
+
+	rows, err := eh.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []waitlist.Entry{}
+	entryIDs := []string{}
+	for rows.Next() {
+		var entry waitlist.Entry
+		if err := waitlist.ScanEntry(rows, &entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan waitlist entry"})
+			return
+		}
+		entries = append(entries, entry)
+		entryIDs = append(entryIDs, entry.ID)
+	}
+
+	// Attach each entry's open offer
+	offerRows, err := eh.db.Query(`
+		SELECT `+waitlist.OfferColumns+`
+		FROM waitlist_offers
+		WHERE entry_id = ANY($1) AND status = $2`,
+		pq.Array(entryIDs), waitlist.OfferPending)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist offers"})
+		return
+	}
+	defer offerRows.Close()
+
+	offers := map[string]*waitlist.Offer{}
+	for offerRows.Next() {
+		var offer waitlist.Offer
+		if err := waitlist.ScanOffer(offerRows, &offer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan waitlist offer"})
+			return
+		}
+		offers[offer.EntryID] = &offer
+	}
+	for i := range entries {
+		entries[i].Offer = offers[entries[i].ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
+
+// LeaveWaitlist cancels a waitlist entry. Patients may cancel their own entries, and providers
+// and admins any entry of a provider in their tenant. An open offer is withdrawn and its slot
+// offered to the next patient.
+func (eh *EventsHandler) LeaveWaitlist(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	entry, ok := eh.lockWaitlistEntry(c, tx, userCtx, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if entry.Status != waitlist.StatusWaiting && entry.Status != waitlist.StatusOffered {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot leave the waitlist once the entry is %s", entry.Status)})
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE waitlist_entries SET status = $2, updated_at = $3 WHERE id = $1`, entry.ID, waitlist.StatusCancelled, time.Now().UTC())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		return
+	}
+
+	var offer waitlist.Offer
+	err = waitlist.ScanOffer(tx.QueryRow(`SELECT `+waitlist.OfferColumns+` FROM waitlist_offers WHERE entry_id = $1 AND status = $2 FOR UPDATE`, entry.ID, waitlist.OfferPending), &offer)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist offer"})
+		return
+	}
+	if err == nil {
+		if err := waitlist.CloseOffer(eh.db, tx, &offer, entry.ProviderID, waitlist.OfferWithdrawn); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw waitlist offer", "details": err.Error()})
+			return
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist successfully"})
+}
+
+// AcceptWaitlistOffer books the slot offered to the calling patient, as a request pending the
+// provider's confirmation. The offer must not have expired.
+func (eh *EventsHandler) AcceptWaitlistOffer(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	offer, entry, ok := eh.lockWaitlistOffer(c, tx, userCtx, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	// The slot is the patient's while the offer holds it; release the hold so it does not
+	// conflict with the booking that replaces it
+	if offer.HoldID != nil {
+		if err := holds.Release(tx, *offer.HoldID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release held slot"})
+			return
+		}
+	}
+
+	title := "Appointment"
+	var appointmentType *appointmenttypes.AppointmentType
+	if entry.AppointmentTypeID != nil {
+		appointmentType, err = appointmenttypes.Load(tx, *entry.AppointmentTypeID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment type"})
+			return
+		}
+		title = appointmentType.Name
+	}
+
+	conflictResult, err := eh.bookingChecker(tx, userCtx, appointmentType).CheckTimeSlotAvailability(entry.ProviderID, offer.StartTime, offer.EndTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability", "details": err.Error()})
+		return
+	}
+	if conflictResult.HasConflict {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                 "Time slot not available",
+			"conflict_type":         conflictResult.ConflictType,
+			"message":               conflictResult.Message,
+			"conflicting_event_ids": conflictResult.ConflictingEventIDs,
+		})
+		return
+	}
+
+	event, err := insertRequest(tx, entry.ProviderID, entry.PatientID, entry.AppointmentTypeID, title, entry.Notes, offer.StartTime, offer.EndTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+		return
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.Exec(`UPDATE waitlist_offers SET status = $2, event_id = $3, responded_at = $4 WHERE id = $1`,
+		offer.ID, waitlist.OfferAccepted, event.ID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept offer"})
+		return
+	}
+	_, err = tx.Exec(`UPDATE waitlist_entries SET status = $2, updated_at = $3 WHERE id = $1`, entry.ID, waitlist.StatusBooked, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+		return
+	}
+
+	if err = auditlog.Record(tx, userCtx.AuditActor(c), auditlog.ActionCreate, auditlog.EntityEvent, event.ID, event.CreatedBy, nil, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit log"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"event": event})
+}
+
+// DeclineWaitlistOffer turns down the slot offered to the calling patient, who keeps their place
+// on the waitlist. The slot is offered to the next patient.
+func (eh *EventsHandler) DeclineWaitlistOffer(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Start transaction
+	tx, err := eh.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	offer, entry, ok := eh.lockWaitlistOffer(c, tx, userCtx, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := waitlist.CloseOffer(eh.db, tx, offer, entry.ProviderID, waitlist.OfferDeclined); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline offer", "details": err.Error()})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Offer declined"})
+}
+
+// lockWaitlistEntry loads and row-locks a waitlist entry the user may manage, after locking the
+// provider's calendar. Writes the error response and returns false otherwise.
+func (eh *EventsHandler) lockWaitlistEntry(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, entryID string) (*waitlist.Entry, bool) {
+	// Find the provider first so their calendar can be locked before the entry row
+	var providerID string
+	if err := tx.QueryRow(`SELECT provider_id FROM waitlist_entries WHERE id = $1`, entryID).Scan(&providerID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist entry"})
+		return nil, false
+	}
+
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return nil, false
+	}
+
+	var query string
+	var args []interface{}
+	if userCtx.IsStaff() {
+		scope, scopeArgs := userCtx.ProviderScope("provider_id", 2)
+		query = `SELECT ` + waitlist.EntryColumns + ` FROM waitlist_entries WHERE id = $1 AND ` + scope + ` FOR UPDATE`
+		args = append([]interface{}{entryID}, scopeArgs...)
+	} else {
+		query = `SELECT ` + waitlist.EntryColumns + ` FROM waitlist_entries WHERE id = $1 AND patient_id = $2 FOR UPDATE`
+		args = []interface{}{entryID, userCtx.UserID}
+	}
+
+	var entry waitlist.Entry
+	if err := waitlist.ScanEntry(tx.QueryRow(query, args...), &entry); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist entry"})
+		return nil, false
+	}
+	return &entry, true
+}
+
+// lockWaitlistOffer loads one of the patient's open offers and its entry, after locking the
+// provider's calendar. Writes the error response and returns false if the offer is missing,
+// answered or expired.
+func (eh *EventsHandler) lockWaitlistOffer(c *gin.Context, tx *sql.Tx, userCtx *auth.UserContext, offerID string) (*waitlist.Offer, *waitlist.Entry, bool) {
+	// Find the provider first so their calendar can be locked before the offer row
+	var providerID string
+	err := tx.QueryRow(`
+		SELECT w.provider_id
+		FROM waitlist_offers o
+		JOIN waitlist_entries w ON w.id = o.entry_id
+		WHERE o.id = $1 AND w.patient_id = $2`,
+		offerID, userCtx.UserID).Scan(&providerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offer"})
+		return nil, nil, false
+	}
+
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock provider calendar"})
+		return nil, nil, false
+	}
+
+	var offer waitlist.Offer
+	if err := waitlist.ScanOffer(tx.QueryRow(`SELECT `+waitlist.OfferColumns+` FROM waitlist_offers WHERE id = $1 FOR UPDATE`, offerID), &offer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offer"})
+		return nil, nil, false
+	}
+
+	if offer.Status != waitlist.OfferPending {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Offer is already %s", offer.Status)})
+		return nil, nil, false
+	}
+	if !offer.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Offer has expired"})
+		return nil, nil, false
+	}
+
+	var entry waitlist.Entry
+	if err := waitlist.ScanEntry(tx.QueryRow(`SELECT `+waitlist.EntryColumns+` FROM waitlist_entries WHERE id = $1 FOR UPDATE`, offer.EntryID), &entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist entry"})
+		return nil, nil, false
+	}
+	return &offer, &entry, true
+}
+
+// offerFreedTime offers the time an event no longer takes up to the provider's waitlist.
+// Writes the error response and returns false on failure.
+func (eh *EventsHandler) offerFreedTime(c *gin.Context, tx *sql.Tx, event *auth.Event) bool {
+	if err := waitlist.OfferFreedTime(eh.db, tx, event.CreatedBy, event.StartTime, event.EndTime); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to offer freed time to the waitlist", "details": err.Error()})
+		return false
+	}
+	return true
+}
+
+// isClockTime reports whether s is a wall-clock time "HH:MM" or "HH:MM:SS"
+func isClockTime(s string) bool {
+	if _, err := time.Parse("15:04", s); err == nil {
+		return true
+	}
+	_, err := time.Parse("15:04:05", s)
+	return err == nil
+}
+
+// normalizeClock renders a wall-clock time as "HH:MM:SS" so times compare as strings
+func normalizeClock(s string) string {
+	if len(s) == len("15:04") {
+		return s + ":00"
+	}
+	return s
+}
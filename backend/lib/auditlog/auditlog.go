@@ -0,0 +1,140 @@
+package auditlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// Entity types recorded in the audit log
+const (
+	EntityEvent        = "event"
+	EntityEventSeries  = "event_series"
+	EntityAvailability = "availability"
+	EntitySchedule     = "schedule"
+	EntityUser         = "user"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx. Handlers pass their transaction
+// so the audit entry is committed (or rolled back) together with the change.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Actor identifies who made a change and from where
+type Actor struct {
+	UserID string
+	Role   string
+	IP     string
+}
+
+// Change holds the before and after value of a single field
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Entry is one recorded change
+type Entry struct {
+	ID         string            `json:"id" db:"id"`
+	ActorID    string            `json:"actor_id" db:"actor_id"`
+	ActorRole  string            `json:"actor_role" db:"actor_role"`
+	Action     string            `json:"action" db:"action"`           // create, update, delete, restore
+	EntityType string            `json:"entity_type" db:"entity_type"` // event, event_series, availability, schedule, user
+	EntityID   string            `json:"entity_id" db:"entity_id"`
+	OwnerID    string            `json:"owner_id" db:"owner_id"` // Provider (or user) whose data changed; used for tenant scoping
+	Changes    map[string]Change `json:"changes" db:"changes"`
+	IPAddress  *string           `json:"ip_address,omitempty" db:"ip_address"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// ignoredFields change on every write and would only add noise to a diff
+var ignoredFields = map[string]bool{"updated_at": true}
+
+// Record writes an audit entry for a change to an entity owned by ownerID. before is nil
+// for creations and after is nil for deletions; otherwise only the fields that differ are kept.
+func Record(db Execer, actor Actor, action, entityType, entityID, ownerID string, before, after interface{}) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	var ip *string
+	if actor.IP != "" {
+		ip = &actor.IP
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO audit_log (id, actor_id, actor_role, action, entity_type, entity_id, owner_id, changes, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		uuid.New().String(), actor.UserID, actor.Role, action, entityType, entityID, ownerID,
+		string(changesJSON), ip, time.Now().UTC(),
+	)
+	return err
+}
+
+// Diff compares the JSON representations of before and after field by field.
+// Either side may be nil, in which case every field of the other side is reported.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for name, value := range beforeFields {
+		if ignoredFields[name] {
+			continue
+		}
+		if newValue := afterFields[name]; !reflect.DeepEqual(value, newValue) {
+			changes[name] = Change{Before: value, After: newValue}
+		}
+	}
+	for name, value := range afterFields {
+		if _, seen := beforeFields[name]; seen || ignoredFields[name] {
+			continue
+		}
+		if value != nil {
+			changes[name] = Change{Before: nil, After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+// fields flattens a value into its top-level JSON fields
+func fields(value interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return result, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
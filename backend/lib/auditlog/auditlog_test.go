@@ -0,0 +1,107 @@
+package auditlog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type record struct {
+	Title     string     `json:"title"`
+	Notes     *string    `json:"notes,omitempty"`
+	Days      []int      `json:"days"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	notes := "Bring referral"
+	deletedAt := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	base := record{Title: "Checkup", Days: []int{1, 3}, UpdatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	withTitle := base
+	withTitle.Title = "Follow-up"
+	withTitle.UpdatedAt = time.Now()
+
+	withNotes := base
+	withNotes.Notes = &notes
+
+	withDays := base
+	withDays.Days = []int{1, 3, 5}
+
+	deleted := base
+	deleted.DeletedAt = &deletedAt
+
+	tests := []struct {
+		name          string
+		before, after interface{}
+		want          map[string]Change
+	}{
+		{
+			name:   "unchanged",
+			before: base,
+			after:  base,
+			want:   map[string]Change{},
+		},
+		{
+			name:   "changed field, updated_at ignored",
+			before: base,
+			after:  withTitle,
+			want:   map[string]Change{"title": {Before: "Checkup", After: "Follow-up"}},
+		},
+		{
+			name:   "field added",
+			before: base,
+			after:  withNotes,
+			want:   map[string]Change{"notes": {Before: nil, After: "Bring referral"}},
+		},
+		{
+			name:   "field removed",
+			before: withNotes,
+			after:  base,
+			want:   map[string]Change{"notes": {Before: "Bring referral", After: nil}},
+		},
+		{
+			name:   "slice changed",
+			before: base,
+			after:  withDays,
+			want:   map[string]Change{"days": {Before: []interface{}{1.0, 3.0}, After: []interface{}{1.0, 3.0, 5.0}}},
+		},
+		{
+			name:   "soft delete",
+			before: &base,
+			after:  &deleted,
+			want:   map[string]Change{"deleted_at": {Before: nil, After: "2026-01-05T09:00:00Z"}},
+		},
+		{
+			name:   "creation reports every field",
+			before: nil,
+			after:  base,
+			want: map[string]Change{
+				"title": {Before: nil, After: "Checkup"},
+				"days":  {Before: nil, After: []interface{}{1.0, 3.0}},
+			},
+		},
+		{
+			name:   "deletion through a nil pointer",
+			before: base,
+			after:  (*record)(nil),
+			want: map[string]Change{
+				"title": {Before: "Checkup", After: nil},
+				"days":  {Before: []interface{}{1.0, 3.0}, After: nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,30 +4,53 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/policy"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/lib/pq"
 )
 
 // ConflictResult represents the result of a conflict check
 type ConflictResult struct {
-	HasConflict  bool   `json:"has_conflict"`
-	ConflictType string `json:"conflict_type,omitempty"` // "date_override", "no_availability", "outside_hours"
-	Message      string `json:"message"`
+	HasConflict         bool     `json:"has_conflict"`
+	ConflictType        string   `json:"conflict_type,omitempty"` // "min_notice", "max_horizon", "date_override", "no_availability", "outside_hours", "overlapping_event", "held_slot"
+	Message             string   `json:"message"`
+	ConflictingEventIDs []string `json:"conflicting_event_ids,omitempty"` // Only for "overlapping_event"
 }
 
 // Availability represents a provider's availability rule in the system
+
 type Availability struct {
-	ID           string     `json:"id" db:"id"`
-	UserID       string     `json:"user_id" db:"user_id"`
-	DayOfWeek    *int       `json:"day_of_week,omitempty" db:"day_of_week"`     // 0=Sunday, 6=Saturday (NULL for overrides)
-	StartTime    *string    `json:"start_time,omitempty" db:"start_time"`       // TIME format "09:00:00" (NULL for overrides)
-	EndTime      *string    `json:"end_time,omitempty" db:"end_time"`           // TIME format "17:00:00" (NULL for overrides)
-	OverrideDate *time.Time `json:"override_date,omitempty" db:"override_date"` // Specific date for override (NULL for recurring)
-	IsAvailable  bool       `json:"is_available" db:"is_available"`             // false for "closed" overrides
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	ID              string     `json:"id" db:"id"`
+	UserID          string     `json:"user_id" db:"user_id"`
+	DayOfWeek       *int       `json:"day_of_week,omitempty" db:"day_of_week"`             // 0=Sunday, 6=Saturday (NULL for overrides)
+	StartTime       *string    `json:"start_time,omitempty" db:"start_time"`               // TIME format "09:00:00" (NULL for overrides)
+	EndTime         *string    `json:"end_time,omitempty" db:"end_time"`                   // TIME format "17:00:00" (NULL for overrides)
+	OverrideDate    *time.Time `json:"override_date,omitempty" db:"override_date"`         // Specific date for override (NULL for recurring)
+	OverrideEndDate *time.Time `json:"override_end_date,omitempty" db:"override_end_date"` // Last day of a date-range override (NULL = single day)
+	IsAvailable     bool       `json:"is_available" db:"is_available"`                     // false for "closed" overrides
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 type ConflictChecker struct {
-	db *sql.DB
+	db           Queryer
+	scope        *auth.UserContext // Tenant scope of the caller (nil = unscoped)
+	exclude      []string          // Events ignored by the overlap check (e.g. the event being rescheduled)
+	scheduleID   string            // Schedule whose weekly rules apply (empty = provider's default)
+	bufferBefore time.Duration     // Free time the checked slot needs before it
+	bufferAfter  time.Duration     // Free time the checked slot needs after it
+	anyTime      bool              // Skip the booking window (minimum notice and horizon)
+	policy       *policy.Policy    // Booking policy of policyOwner, loaded on first use
+	policyOwner  string
 }
 
 func NewConflictChecker(db *sql.DB) *ConflictChecker {
@@ -36,158 +59,264 @@ func NewConflictChecker(db *sql.DB) *ConflictChecker {
 	}
 }
 
+// InTx runs every lookup inside tx, so the check sees the same snapshot as the write that follows it
+func (cc *ConflictChecker) InTx(tx *sql.Tx) *ConflictChecker {
+	cc.db = tx
+	return cc
+}
+
+// WithSchedule evaluates weekly rules from the given schedule instead of the provider's default
+func (cc *ConflictChecker) WithSchedule(scheduleID string) *ConflictChecker {
+	cc.scheduleID = scheduleID
+	return cc
+}
+
+// WithBuffers requires free time (in minutes) before and after the checked slot, as set by
+// its appointment type. Buffers only affect the overlap check, not the availability hours.
+func (cc *ConflictChecker) WithBuffers(beforeMinutes, afterMinutes int) *ConflictChecker {
+	cc.bufferBefore = time.Duration(beforeMinutes) * time.Minute
+	cc.bufferAfter = time.Duration(afterMinutes) * time.Minute
+	return cc
+}
+
+// IgnoreBookingWindow skips the minimum notice and maximum horizon of the provider's booking
+// policy, for events that are not new bookings (e.g. restoring a deleted appointment)
+func (cc *ConflictChecker) IgnoreBookingWindow() *ConflictChecker {
+	cc.anyTime = true
+	return cc
+}
+
+// ExcludeEvents ignores the given events in the overlap check, so an event being
+// rescheduled does not conflict with its own current time
+func (cc *ConflictChecker) ExcludeEvents(eventIDs ...string) *ConflictChecker {
+	cc.exclude = append(cc.exclude, eventIDs...)
+	return cc
+}
+
+// LockProvider takes a transaction-scoped advisory lock on the provider's calendar.
+// Bookings for the same provider are serialized until the transaction commits or rolls back,
+// so two concurrent requests cannot both pass the overlap check for the same slot.
+func LockProvider(tx *sql.Tx, providerID string) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", providerID)
+	return err
+}
+
+// WithScope restricts every lookup to providers within the caller's tenant.
+// Providers outside the tenant are reported as having no availability.
+func (cc *ConflictChecker) WithScope(userCtx *auth.UserContext) *ConflictChecker {
+	cc.scope = userCtx
+	return cc
+}
+
+// scopeClause returns the tenant predicate on the provider column starting at argIndex
+func (cc *ConflictChecker) scopeClause(column string, argIndex int) (string, []interface{}) {
+	if cc.scope == nil {
+		return "TRUE", nil
+	}
+	return cc.scope.ProviderScope(column, argIndex)
+}
+
+// CheckTimeSlotAvailability checks a time slot against the provider's booking window,
+// availability rules, date overrides, existing events (appointments and blocks) and held slots
 func (cc *ConflictChecker) CheckTimeSlotAvailability(
 	providerID string,
 	startTime time.Time,
 	endTime time.Time,
 ) (*ConflictResult, error) {
 
-	// STEP 1: Check for date override (ABSOLUTE BLOCK)
-	override, err := cc.getDateOverride(providerID, startTime)
+	bookingPolicy, err := cc.bookingPolicy(providerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check date override: %w", err)
+		return nil, fmt.Errorf("failed to load booking policy: %w", err)
 	}
 
-	if override != nil {
-		// If override is not available, block completely
-		if !override.IsAvailable {
+	// STEP 0: Check the slot is within the booking window (minimum notice and horizon)
+	if !cc.anyTime {
+		if violation := bookingPolicy.CheckBooking(startTime, time.Now().UTC()); violation != nil {
 			return &ConflictResult{
 				HasConflict:  true,
-				ConflictType: "date_override",
-				Message:      "Provider not available on this date",
+				ConflictType: violation.Rule,
+				Message:      violation.Message,
 			}, nil
 		}
+	}
 
-		// If override is available but has custom hours, check those hours
-		if override.StartTime != nil && override.EndTime != nil {
-			if !cc.isTimeWithinHours(startTime, endTime, *override.StartTime, *override.EndTime) {
-				return &ConflictResult{
-					HasConflict:  true,
-					ConflictType: "outside_hours",
-					Message:      fmt.Sprintf("Time outside available hours (%s - %s)", *override.StartTime, *override.EndTime),
-				}, nil
-			}
-		}
+	result, err := cc.checkAvailabilityRules(providerID, startTime, endTime)
+	if err != nil || result.HasConflict {
+		return result, err
+	}
 
-		// Override is available and time is within custom hours (or no custom hours)
+	// STEP 4: Check for double-booking against existing events
+	overlapping, err := cc.getOverlappingEvents(providerID, bookingPolicy, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check overlapping events: %w", err)
+	}
+
+	if len(overlapping) > 0 {
 		return &ConflictResult{
-			HasConflict: false,
-			Message:     "Time slot available",
+			HasConflict:         true,
+			ConflictType:        "overlapping_event",
+			Message:             fmt.Sprintf("Provider already has %d overlapping event(s) at this time", len(overlapping)),
+			ConflictingEventIDs: overlapping,
 		}, nil
 	}
 
-	// STEP 2: Get regular availability for this day
-	availability, err := cc.getRegularAvailability(providerID, startTime)
+	// STEP 5: Check the slot is not held for someone (e.g. offered to a waitlisted patient)
+	held, err := cc.isHeld(providerID, bookingPolicy, startTime, endTime)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check regular availability: %w", err)
+		return nil, fmt.Errorf("failed to check held slots: %w", err)
 	}
 
-	if availability == nil {
+	if held {
 		return &ConflictResult{
 			HasConflict:  true,
-			ConflictType: "no_availability",
-			Message:      "Provider not available on this day",
+			ConflictType: "held_slot",
+			Message:      "This time is being held for another booking",
 		}, nil
 	}
 
-	// STEP 3: Check if time is within available hours
-	if !cc.isTimeWithinHours(startTime, endTime, *availability.StartTime, *availability.EndTime) {
+	return result, nil
+}
+
+// checkAvailabilityRules checks a time slot against date overrides and regular weekly availability
+func (cc *ConflictChecker) checkAvailabilityRules(
+	providerID string,
+	startTime time.Time,
+	endTime time.Time,
+) (*ConflictResult, error) {
+
+	schedule, err := LoadSchedule(cc.db, providerID, cc.scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedule: %w", err)
+	}
+
+	if schedule == nil {
 		return &ConflictResult{
 			HasConflict:  true,
-			ConflictType: "outside_hours",
-			Message:      fmt.Sprintf("Time outside available hours (%s - %s)", *availability.StartTime, *availability.EndTime),
+			ConflictType: "no_availability",
+			Message:      "Schedule not found for this provider",
 		}, nil
 	}
 
-	// No conflicts found - booking allowed
-	return &ConflictResult{
-		HasConflict: false,
-		Message:     "Time slot available",
-	}, nil
-}
-
-// getDateOverride checks for a specific date override
-func (cc *ConflictChecker) getDateOverride(providerID string, requestTime time.Time) (*Availability, error) {
-	// Get date part only (ignore time)
-	requestDate := requestTime.Truncate(24 * time.Hour)
+	// Rules are defined in the schedule's timezone, so the date, weekday and
+	// hours are all evaluated there rather than in the request's offset
+	loc := schedule.Location
+	localDate := timezone.Date(startTime, loc)
 
-	query := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
-		FROM availability
-		WHERE user_id = $1 AND override_date = $2`
+	// STEPS 1-2: Resolve the day's windows from date overrides (ABSOLUTE) or the weekly rules
+	windows, fromOverride, err := cc.DayWindows(providerID, schedule, localDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check availability rules: %w", err)
+	}
 
-	var availability Availability
-	err := cc.db.QueryRow(query, providerID, requestDate).Scan(
-		&availability.ID, &availability.UserID, &availability.DayOfWeek,
-		&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
-		&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
-	)
+	if len(windows) == 0 {
+		if fromOverride {
+			return &ConflictResult{
+				HasConflict:  true,
+				ConflictType: "date_override",
+				Message:      "Provider not available on this date",
+			}, nil
+		}
+		return &ConflictResult{
+			HasConflict:  true,
+			ConflictType: "no_availability",
+			Message:      "Provider not available on this day",
+		}, nil
+	}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No override found
+	// STEP 3: Check if time is within one of the available windows
+	for _, window := range windows {
+		if window.Contains(startTime, endTime) {
+			// No conflicts found - booking allowed
+			return &ConflictResult{
+				HasConflict: false,
+				Message:     "Time slot available",
+			}, nil
 		}
-		return nil, err
 	}
 
-	return &availability, nil
+	return &ConflictResult{
+		HasConflict:  true,
+		ConflictType: "outside_hours",
+		Message:      fmt.Sprintf("Time outside available hours (%s)", formatWindows(windows, loc)),
+	}, nil
 }
 
-// getRegularAvailability gets the regular weekly availability rule for a given day
-func (cc *ConflictChecker) getRegularAvailability(providerID string, requestTime time.Time) (*Availability, error) {
-	// Get day of week (0=Sunday, 1=Monday, ..., 6=Saturday)
-	dayOfWeek := int(requestTime.Weekday())
-
-	query := `
-		SELECT id, user_id, day_of_week, start_time, end_time, override_date, is_available, created_at, updated_at
-		FROM availability
-		WHERE user_id = $1 AND day_of_week = $2 AND override_date IS NULL AND is_available = true`
-
-	var availability Availability
-	err := cc.db.QueryRow(query, providerID, dayOfWeek).Scan(
-		&availability.ID, &availability.UserID, &availability.DayOfWeek,
-		&availability.StartTime, &availability.EndTime, &availability.OverrideDate,
-		&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
-	)
+// bookingPolicy returns the provider's booking policy, loading it once per checker
+func (cc *ConflictChecker) bookingPolicy(providerID string) (*policy.Policy, error) {
+	if cc.policy != nil && cc.policyOwner == providerID {
+		return cc.policy, nil
+	}
 
+	p, err := policy.ForProvider(cc.db, providerID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No availability rule found
-		}
 		return nil, err
 	}
-	return &availability, nil
+	cc.policy, cc.policyOwner = p, providerID
+	return p, nil
 }
 
-// isTimeWithinHours checks if the requested time slot is within the available hours (all UTC)
-func (cc *ConflictChecker) isTimeWithinHours(startTime, endTime time.Time, availableStart, availableEnd string) bool {
-	// Parse available times (they're stored as "15:04:05" format in database)
-	availStartTime, err := time.Parse("15:04:05", availableStart)
-	if err != nil {
-		return false
+// getOverlappingEvents returns the IDs of the provider's live, non-cancelled events that overlap the
+// time range. The range is widened by the checker's buffers and each event by its appointment type's
+// buffers, or the policy's buffers around every event when those are longer.
+func (cc *ConflictChecker) getOverlappingEvents(providerID string, bookingPolicy *policy.Policy, startTime, endTime time.Time) ([]string, error) {
+	query := `
+		SELECT e.id
+		FROM events e
+		LEFT JOIN appointment_types at ON at.id = e.appointment_type_id
+		WHERE e.created_by = $1
+		AND e.start_time - make_interval(mins => GREATEST(COALESCE(at.buffer_before_minutes, 0), $4)) < $3
+		AND e.end_time + make_interval(mins => GREATEST(COALESCE(at.buffer_after_minutes, 0), $5)) > $2
+		AND e.status != 'cancelled'
+		AND e.deleted_at IS NULL`
+	args := []interface{}{
+		providerID, startTime.Add(-cc.bufferBefore), endTime.Add(cc.bufferAfter),
+		bookingPolicy.BufferBeforeMinutes, bookingPolicy.BufferAfterMinutes,
+	}
+
+	if len(cc.exclude) > 0 {
+		query += fmt.Sprintf(" AND NOT (e.id = ANY($%d))", len(args)+1)
+		args = append(args, pq.Array(cc.exclude))
 	}
 
-	availEndTime, err := time.Parse("15:04:05", availableEnd)
+	scope, scopeArgs := cc.scopeClause("e.created_by", len(args)+1)
+	query += " AND " + scope + " ORDER BY e.start_time"
+	args = append(args, scopeArgs...)
+
+	rows, err := cc.db.Query(query, args...)
 	if err != nil {
-		return false
+		return nil, err
 	}
+	defer rows.Close()
 
-	// The time.Time object preserves the original timezone from the request
-	// When frontend sends "2024-01-15T14:00:00-05:00", Go parses it with that timezone
-	// So .Hour() returns 14 (2 PM in EST), not 19 (7 PM UTC)
-	// This works correctly as long as availability hours (09:00-17:00) are in the same timezone
-	requestStartHour := startTime.Hour()
-	requestStartMin := startTime.Minute()
-	requestEndHour := endTime.Hour()
-	requestEndMin := endTime.Minute()
+	var eventIDs []string
+	for rows.Next() {
+		var eventID string
+		if err := rows.Scan(&eventID); err != nil {
+			return nil, err
+		}
+		eventIDs = append(eventIDs, eventID)
+	}
 
-	// Convert to minutes since midnight for easier comparison
-	requestStartMinutes := requestStartHour*60 + requestStartMin
-	requestEndMinutes := requestEndHour*60 + requestEndMin
+	return eventIDs, rows.Err()
+}
 
-	availableStartMinutes := availStartTime.Hour()*60 + availStartTime.Minute()
-	availableEndMinutes := availEndTime.Hour()*60 + availEndTime.Minute()
+// isHeld reports whether an unexpired hold on the provider's calendar overlaps the time range.
+// Holds are widened by the policy's buffers, as events are.
+func (cc *ConflictChecker) isHeld(providerID string, bookingPolicy *policy.Policy, startTime, endTime time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM slot_holds
+			WHERE provider_id = $1
+			AND start_time - make_interval(mins => $4) < $3
+			AND end_time + make_interval(mins => $5) > $2
+			AND expires_at > $6
+		)`
 
-	// Check if the entire requested time slot is within available hours
-	return requestStartMinutes >= availableStartMinutes && requestEndMinutes <= availableEndMinutes
-}
\ No newline at end of file
+	var held bool
+	err := cc.db.QueryRow(
+		query,
+		providerID, startTime.Add(-cc.bufferBefore), endTime.Add(cc.bufferAfter),
+		bookingPolicy.BufferBeforeMinutes, bookingPolicy.BufferAfterMinutes, time.Now().UTC(),
+	).Scan(&held)
+	return held, err
+}
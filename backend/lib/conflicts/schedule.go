@@ -0,0 +1,53 @@
+package conflicts
+
+import (
+	"database/sql"
+	"time"
+
+	"emr-calendar-backend/lib/timezone"
+)
+
+// ProviderSchedule identifies the availability schedule a provider's weekly rules are read from
+type ProviderSchedule struct {
+	ID       string         // Empty when the provider has no schedule yet
+	Location *time.Location // Timezone the schedule's hours are defined in
+}
+
+// LoadSchedule returns the provider's schedule with the given ID, or their default schedule
+// when scheduleID is empty. It returns nil if scheduleID does not belong to the provider.
+// Providers without any schedule fall back to their profile timezone and have no weekly rules.
+func LoadSchedule(db Queryer, providerID, scheduleID string) (*ProviderSchedule, error) {
+	var query string
+	var args []interface{}
+
+	if scheduleID != "" {
+		query = `SELECT id, timezone FROM schedules WHERE id = $1 AND user_id = $2`
+		args = []interface{}{scheduleID, providerID}
+	} else {
+		query = `SELECT id, timezone FROM schedules WHERE user_id = $1 AND is_default = true`
+		args = []interface{}{providerID}
+	}
+
+	var id, name string
+	err := db.QueryRow(query, args...).Scan(&id, &name)
+	if err == sql.ErrNoRows {
+		if scheduleID != "" {
+			return nil, nil
+		}
+
+		loc, err := timezone.ForProvider(db, providerID)
+		if err != nil {
+			return nil, err
+		}
+		return &ProviderSchedule{Location: loc}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := timezone.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderSchedule{ID: id, Location: loc}, nil
+}
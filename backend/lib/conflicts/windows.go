@@ -0,0 +1,265 @@
+package conflicts
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"emr-calendar-backend/lib/timezone"
+)
+
+// TimeWindow is an absolute span of time during which a provider is available
+type TimeWindow struct {
+	Start time.Time `json:"start_time"`
+	End   time.Time `json:"end_time"`
+}
+
+// Contains reports whether the whole range [start, end) lies within the window
+func (w TimeWindow) Contains(start, end time.Time) bool {
+	return !start.Before(w.Start) && !end.After(w.End)
+}
+
+// DayWindows returns the provider's available windows on a local calendar date, merged and
+// sorted. Date overrides replace the schedule's weekly rules for that date entirely; the
+// second return value reports whether they did. Every rule for the day counts, so split
+// shifts (e.g. 08:00-12:00 and 13:00-17:00) yield several windows.
+func (cc *ConflictChecker) DayWindows(providerID string, schedule *ProviderSchedule, localDate time.Time) ([]TimeWindow, bool, error) {
+	overrides, err := cc.getDateOverrides(providerID, localDate)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var rules []Availability
+	if len(overrides) == 0 {
+		rules, err = cc.getRegularAvailability(providerID, schedule.ID, localDate)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	windows, fromOverride := dayWindows(overrides, rules, localDate, schedule.Location)
+	return windows, fromOverride, nil
+}
+
+// RangeWindows returns the provider's available windows on every local calendar date from
+// startDate to endDate (inclusive), keyed by date as "2006-01-02". The overrides and weekly
+// rules for the whole range are loaded with one query each.
+func (cc *ConflictChecker) RangeWindows(providerID string, schedule *ProviderSchedule, startDate, endDate time.Time) (map[string][]TimeWindow, error) {
+	overrides, err := cc.getRangeOverrides(providerID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	weeklyRules, err := cc.getWeeklyRules(providerID, schedule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	windowsByDate := make(map[string][]TimeWindow)
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		var dayOverrides, dayRules []Availability
+		for _, override := range overrides {
+			if coversDate(override, date) {
+				dayOverrides = append(dayOverrides, override)
+			}
+		}
+		for _, rule := range weeklyRules {
+			if rule.DayOfWeek != nil && *rule.DayOfWeek == int(date.Weekday()) {
+				dayRules = append(dayRules, rule)
+			}
+		}
+
+		windows, _ := dayWindows(dayOverrides, dayRules, date, schedule.Location)
+		windowsByDate[date.Format("2006-01-02")] = windows
+	}
+
+	return windowsByDate, nil
+}
+
+// dayWindows resolves the windows of a local date from the overrides covering it, which
+// replace the weekly rules entirely when there are any, or else from the day's weekly rules
+func dayWindows(overrides, rules []Availability, localDate time.Time, loc *time.Location) ([]TimeWindow, bool) {
+	if len(overrides) > 0 {
+		windows := []TimeWindow{}
+		for _, override := range overrides {
+			if !override.IsAvailable {
+				continue
+			}
+
+			// An available override without hours opens the whole day
+			if override.StartTime == nil || override.EndTime == nil {
+				start, end := timezone.StartOfDay(localDate, loc)
+				windows = append(windows, TimeWindow{Start: start, End: end})
+				continue
+			}
+
+			if window, ok := ruleWindow(override, localDate, loc); ok {
+				windows = append(windows, window)
+			}
+		}
+		return mergeWindows(windows), true
+	}
+
+	windows := []TimeWindow{}
+	for _, rule := range rules {
+		if window, ok := ruleWindow(rule, localDate, loc); ok {
+			windows = append(windows, window)
+		}
+	}
+	return mergeWindows(windows), false
+}
+
+// coversDate reports whether a single-day or date-range override applies to the local date
+func coversDate(override Availability, localDate time.Time) bool {
+	if override.OverrideDate == nil {
+		return false
+	}
+	last := *override.OverrideDate
+	if override.OverrideEndDate != nil {
+		last = *override.OverrideEndDate
+	}
+	day := localDate.Format("2006-01-02")
+	return override.OverrideDate.Format("2006-01-02") <= day && last.Format("2006-01-02") >= day
+}
+
+// getDateOverrides gets every override row covering the provider's local date,
+// including date-range overrides that span it
+func (cc *ConflictChecker) getDateOverrides(providerID string, localDate time.Time) ([]Availability, error) {
+	scope, scopeArgs := cc.scopeClause("user_id", 3)
+	query := `
+		SELECT id, user_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
+		FROM availability
+		WHERE user_id = $1 AND override_date <= $2 AND COALESCE(override_end_date, override_date) >= $2 AND ` + scope + `
+		ORDER BY start_time ASC`
+	args := append([]interface{}{providerID, localDate.Format("2006-01-02")}, scopeArgs...)
+
+	return cc.queryRules(query, args...)
+}
+
+// getRangeOverrides gets every override row covering any of the provider's local dates
+// from startDate to endDate
+func (cc *ConflictChecker) getRangeOverrides(providerID string, startDate, endDate time.Time) ([]Availability, error) {
+	scope, scopeArgs := cc.scopeClause("user_id", 4)
+	query := `
+		SELECT id, user_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
+		FROM availability
+		WHERE user_id = $1 AND override_date <= $3 AND COALESCE(override_end_date, override_date) >= $2 AND ` + scope + `
+		ORDER BY start_time ASC`
+	args := append([]interface{}{providerID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}, scopeArgs...)
+
+	return cc.queryRules(query, args...)
+}
+
+// getWeeklyRules gets the schedule's available weekly rules for every day of the week
+func (cc *ConflictChecker) getWeeklyRules(providerID, scheduleID string) ([]Availability, error) {
+	if scheduleID == "" {
+		return nil, nil // Provider has not set up a schedule yet
+	}
+
+	scope, scopeArgs := cc.scopeClause("user_id", 3)
+	query := `
+		SELECT id, user_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
+		FROM availability
+		WHERE user_id = $1 AND schedule_id = $2 AND override_date IS NULL AND is_available = true AND ` + scope + `
+		ORDER BY start_time ASC`
+	args := append([]interface{}{providerID, scheduleID}, scopeArgs...)
+
+	return cc.queryRules(query, args...)
+}
+
+// getRegularAvailability gets every weekly rule of the schedule for the provider's local date
+func (cc *ConflictChecker) getRegularAvailability(providerID, scheduleID string, localDate time.Time) ([]Availability, error) {
+	if scheduleID == "" {
+		return nil, nil // Provider has not set up a schedule yet
+	}
+
+	// Get day of week (0=Sunday, 1=Monday, ..., 6=Saturday)
+	dayOfWeek := int(localDate.Weekday())
+
+	scope, scopeArgs := cc.scopeClause("user_id", 4)
+	query := `
+		SELECT id, user_id, day_of_week, start_time, end_time, override_date, override_end_date, is_available, created_at, updated_at
+		FROM availability
+		WHERE user_id = $1 AND schedule_id = $2 AND day_of_week = $3 AND override_date IS NULL AND is_available = true AND ` + scope + `
+		ORDER BY start_time ASC`
+	args := append([]interface{}{providerID, scheduleID, dayOfWeek}, scopeArgs...)
+
+	return cc.queryRules(query, args...)
+}
+
+// queryRules runs an availability query and scans every row
+func (cc *ConflictChecker) queryRules(query string, args ...interface{}) ([]Availability, error) {
+	rows, err := cc.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Availability
+	for rows.Next() {
+		var availability Availability
+		err := rows.Scan(
+			&availability.ID, &availability.UserID, &availability.DayOfWeek,
+			&availability.StartTime, &availability.EndTime, &availability.OverrideDate, &availability.OverrideEndDate,
+			&availability.IsAvailable, &availability.CreatedAt, &availability.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, availability)
+	}
+
+	return rules, rows.Err()
+}
+
+// ruleWindow resolves a rule's wall-clock hours on localDate to absolute instants in loc,
+// which keeps DST transition days correct regardless of the offset a request was sent with
+func ruleWindow(rule Availability, localDate time.Time, loc *time.Location) (TimeWindow, bool) {
+	if rule.StartTime == nil || rule.EndTime == nil {
+		return TimeWindow{}, false
+	}
+
+	start, err := timezone.WallClock(localDate, *rule.StartTime, loc)
+	if err != nil {
+		return TimeWindow{}, false
+	}
+
+	end, err := timezone.WallClock(localDate, *rule.EndTime, loc)
+	if err != nil || !end.After(start) {
+		return TimeWindow{}, false
+	}
+
+	return TimeWindow{Start: start, End: end}, true
+}
+
+// mergeWindows sorts windows and joins the ones that overlap or touch,
+// so a booking may span two back-to-back rules
+func mergeWindows(windows []TimeWindow) []TimeWindow {
+	if len(windows) < 2 {
+		return windows
+	}
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+
+	merged := []TimeWindow{windows[0]}
+	for _, window := range windows[1:] {
+		last := &merged[len(merged)-1]
+		if !window.Start.After(last.End) {
+			if window.End.After(last.End) {
+				last.End = window.End
+			}
+			continue
+		}
+		merged = append(merged, window)
+	}
+	return merged
+}
+
+// formatWindows renders windows as wall-clock ranges in loc, e.g. "08:00 - 12:00, 13:00 - 17:00"
+func formatWindows(windows []TimeWindow, loc *time.Location) string {
+	parts := make([]string, 0, len(windows))
+	for _, window := range windows {
+		parts = append(parts, window.Start.In(loc).Format("15:04")+" - "+window.End.In(loc).Format("15:04"))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,125 @@
+package conflicts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeWindows(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2026, 1, 5, hour, min, 0, 0, time.UTC) }
+	window := func(startHour, startMin, endHour, endMin int) TimeWindow {
+		return TimeWindow{Start: at(startHour, startMin), End: at(endHour, endMin)}
+	}
+
+	tests := []struct {
+		name    string
+		windows []TimeWindow
+		want    []TimeWindow
+	}{
+		{"none", nil, nil},
+		{"single", []TimeWindow{window(9, 0, 12, 0)}, []TimeWindow{window(9, 0, 12, 0)}},
+		{
+			name:    "split shift stays split",
+			windows: []TimeWindow{window(13, 0, 17, 0), window(8, 0, 12, 0)},
+			want:    []TimeWindow{window(8, 0, 12, 0), window(13, 0, 17, 0)},
+		},
+		{
+			name:    "back to back rules join",
+			windows: []TimeWindow{window(8, 0, 12, 0), window(12, 0, 17, 0)},
+			want:    []TimeWindow{window(8, 0, 17, 0)},
+		},
+		{
+			name:    "overlapping rules join",
+			windows: []TimeWindow{window(10, 0, 14, 0), window(8, 0, 11, 30)},
+			want:    []TimeWindow{window(8, 0, 14, 0)},
+		},
+		{
+			name:    "contained rule is absorbed",
+			windows: []TimeWindow{window(8, 0, 17, 0), window(9, 0, 10, 0), window(18, 0, 19, 0)},
+			want:    []TimeWindow{window(8, 0, 17, 0), window(18, 0, 19, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeWindows(tt.windows)
+			if len(got) != len(tt.want) {
+				t.Fatalf("mergeWindows() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("window %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDayWindows(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	str := func(s string) *string { return &s }
+	rule := func(start, end string) Availability {
+		return Availability{StartTime: str(start), EndTime: str(end), IsAvailable: true}
+	}
+	localDate := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC) // Spring forward in New York
+	at := func(hour int) time.Time { return time.Date(2026, 3, 8, hour, 0, 0, 0, loc) }
+
+	tests := []struct {
+		name         string
+		overrides    []Availability
+		rules        []Availability
+		want         []TimeWindow
+		fromOverride bool
+	}{
+		{
+			name:  "weekly rules are merged",
+			rules: []Availability{rule("13:00", "17:00"), rule("08:00", "12:00"), rule("12:00", "13:00")},
+			want:  []TimeWindow{{Start: at(8), End: at(17)}},
+		},
+		{
+			name:         "overrides replace the weekly rules",
+			overrides:    []Availability{rule("10:00", "11:00")},
+			rules:        []Availability{rule("08:00", "17:00")},
+			want:         []TimeWindow{{Start: at(10), End: at(11)}},
+			fromOverride: true,
+		},
+		{
+			name:         "closed override leaves no windows",
+			overrides:    []Availability{{IsAvailable: false}},
+			rules:        []Availability{rule("08:00", "17:00")},
+			want:         nil,
+			fromOverride: true,
+		},
+		{
+			name:         "open override without hours covers the whole day",
+			overrides:    []Availability{{IsAvailable: true}},
+			want:         []TimeWindow{{Start: at(0), End: time.Date(2026, 3, 9, 0, 0, 0, 0, loc)}},
+			fromOverride: true,
+		},
+		{
+			name:  "rules ending before they start are ignored",
+			rules: []Availability{rule("17:00", "08:00")},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fromOverride := dayWindows(tt.overrides, tt.rules, localDate, loc)
+			if fromOverride != tt.fromOverride {
+				t.Errorf("fromOverride = %v, want %v", fromOverride, tt.fromOverride)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("dayWindows() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("window %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
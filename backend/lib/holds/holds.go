@@ -0,0 +1,82 @@
+package holds
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Hold reserves a provider's slot for one user until it expires. Until then, conflict checks
+// and slot listings treat the slot as taken.
+type Hold struct {
+	ID         string    `json:"id" db:"id"`
+	ProviderID string    `json:"provider_id" db:"provider_id"`
+	UserID     string    `json:"user_id" db:"user_id"` // Who the slot is held for
+	StartTime  time.Time `json:"start_time" db:"start_time"`
+	EndTime    time.Time `json:"end_time" db:"end_time"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Place holds the provider's slot from start to end for userID until expiresAt. Callers must
+// hold the provider's calendar lock and have checked the slot is free.
+func Place(db Execer, providerID, userID string, start, end, expiresAt time.Time) (*Hold, error) {
+	hold := &Hold{
+		ID:         uuid.New().String(),
+		ProviderID: providerID,
+		UserID:     userID,
+		StartTime:  start,
+		EndTime:    end,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO slot_holds (id, provider_id, user_id, start_time, end_time, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		hold.ID, hold.ProviderID, hold.UserID, hold.StartTime, hold.EndTime, hold.ExpiresAt, hold.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// Lock loads and row-locks a hold, returning sql.ErrNoRows if it does not exist
+func Lock(tx *sql.Tx, holdID string) (*Hold, error) {
+	var hold Hold
+	err := tx.QueryRow(`
+		SELECT id, provider_id, user_id, start_time, end_time, expires_at, created_at
+		FROM slot_holds
+		WHERE id = $1
+		FOR UPDATE`,
+		holdID,
+	).Scan(&hold.ID, &hold.ProviderID, &hold.UserID, &hold.StartTime, &hold.EndTime, &hold.ExpiresAt, &hold.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// Covers reports whether the hold reserves the provider's time from start to end
+func (h *Hold) Covers(providerID string, start, end time.Time) bool {
+	return h.ProviderID == providerID && !start.Before(h.StartTime) && !end.After(h.EndTime)
+}
+
+// Release frees a held slot
+func Release(db Execer, holdID string) error {
+	_, err := db.Exec(`DELETE FROM slot_holds WHERE id = $1`, holdID)
+	return err
+}
+
+// DeleteExpired removes holds that have expired; they no longer block anything
+func DeleteExpired(db Execer, now time.Time) error {
+	_, err := db.Exec(`DELETE FROM slot_holds WHERE expires_at <= $1`, now)
+	return err
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"database/sql"
+	"time"
+
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notification sent about appointments and team membership
+const (
+	KindAppointmentApproved = "appointment_approved"
+	KindAppointmentDeclined = "appointment_declined"
+	KindTimeProposed        = "time_proposed"
+	KindProposalAccepted    = "proposal_accepted"
+	KindWaitlistOffer       = "waitlist_offer"
+	KindTeamInvitation      = "team_invitation"
+	KindSeriesConflict      = "series_conflict" // A new occurrence of an open-ended series was not available
+)
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Execer is satisfied by both *sql.DB and *sql.Tx. Handlers pass their transaction
+// so the notification is only delivered if the change it describes is committed.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Notification is a message delivered to a user's inbox
+type Notification struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"` // Recipient
+	Kind      string     `json:"kind" db:"kind"`
+	EventID   *string    `json:"event_id,omitempty" db:"event_id"` // Appointment the notification is about
+	Message   string     `json:"message" db:"message"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Send delivers a notification to userID about an appointment (eventID may be empty)
+func Send(db Execer, userID, kind, eventID, message string) error {
+	var event *string
+	if eventID != "" {
+		event = &eventID
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO notifications (id, user_id, kind, event_id, message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		uuid.New().String(), userID, kind, event, message, time.Now().UTC(),
+	)
+	return err
+}
+
+// FormatTime renders t in the recipient's own timezone for a message, falling back to UTC
+func FormatTime(db Queryer, recipientID string, t time.Time) string {
+	loc, err := timezone.ForProvider(db, recipientID) // Reads users.timezone, which every user has
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("Mon Jan 2, 2006 at 15:04 MST")
+}
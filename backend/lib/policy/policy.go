@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Scopes a policy can be defined at. A provider's own policy wins over their team's;
+// providers without either get the unrestricted default.
+const (
+	ScopeProvider = "provider"
+	ScopeTeam     = "team"
+	ScopeDefault  = "default"
+)
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Policy is a provider's booking policy: when appointments can be booked, the free time kept
+// around them, and when they can be cancelled or rescheduled
+type Policy struct {
+	ID                      *string `json:"id,omitempty" db:"id"`
+	Scope                   string  `json:"scope"` // provider, team or default
+	ProviderID              *string `json:"provider_id,omitempty" db:"user_id"`
+	TeamID                  *string `json:"team_id,omitempty" db:"team_id"`
+	MinNoticeMinutes        int     `json:"min_notice_minutes" db:"min_notice_minutes"`               // Appointments must be booked at least this far ahead
+	MaxHorizonDays          *int    `json:"max_horizon_days,omitempty" db:"max_horizon_days"`         // Appointments can be booked at most this far ahead; NULL = no limit
+	BufferBeforeMinutes     int     `json:"buffer_before_minutes" db:"buffer_before_minutes"`         // Free time kept before every event
+	BufferAfterMinutes      int     `json:"buffer_after_minutes" db:"buffer_after_minutes"`           // Free time kept after every event
+	CancelNoticeMinutes     int     `json:"cancel_notice_minutes" db:"cancel_notice_minutes"`         // Cancellations closer to the start are refused
+	RescheduleNoticeMinutes int     `json:"reschedule_notice_minutes" db:"reschedule_notice_minutes"` // Reschedules closer to the start are refused
+	MaxReschedules          *int    `json:"max_reschedules,omitempty" db:"max_reschedules"`           // NULL = unlimited
+	LateCancelMinutes       int     `json:"late_cancel_minutes" db:"late_cancel_minutes"`             // Cancellations closer to the start are marked late
+}
+
+// Request is the payload for setting a provider's or team's policy
+type Request struct {
+	ProviderID              *string `json:"provider_id"` // Provider policies only; admins may set another provider's policy
+	MinNoticeMinutes        int     `json:"min_notice_minutes" binding:"min=0"`
+	MaxHorizonDays          *int    `json:"max_horizon_days" binding:"omitempty,min=1"` // Omit for no limit
+	BufferBeforeMinutes     int     `json:"buffer_before_minutes" binding:"min=0,max=1440"`
+	BufferAfterMinutes      int     `json:"buffer_after_minutes" binding:"min=0,max=1440"`
+	CancelNoticeMinutes     int     `json:"cancel_notice_minutes" binding:"min=0"`
+	RescheduleNoticeMinutes int     `json:"reschedule_notice_minutes" binding:"min=0"`
+	MaxReschedules          *int    `json:"max_reschedules" binding:"omitempty,min=0"` // Omit for unlimited
+	LateCancelMinutes       int     `json:"late_cancel_minutes" binding:"min=0"`
+}
+
+// Settings returns the policy values carried by the request
+func (r *Request) Settings() *Policy {
+	return &Policy{
+		MinNoticeMinutes:        r.MinNoticeMinutes,
+		MaxHorizonDays:          r.MaxHorizonDays,
+		BufferBeforeMinutes:     r.BufferBeforeMinutes,
+		BufferAfterMinutes:      r.BufferAfterMinutes,
+		CancelNoticeMinutes:     r.CancelNoticeMinutes,
+		RescheduleNoticeMinutes: r.RescheduleNoticeMinutes,
+		MaxReschedules:          r.MaxReschedules,
+		LateCancelMinutes:       r.LateCancelMinutes,
+	}
+}
+
+// Violation explains why a change breaks the policy
+type Violation struct {
+	Rule    string `json:"rule"` // min_notice, max_horizon, cancel_notice, reschedule_notice or max_reschedules
+	Message string `json:"message"`
+}
+
+// Columns lists the columns selected for every policy, in the order Scan expects
+const Columns = `id, user_id, team_id, min_notice_minutes, max_horizon_days, buffer_before_minutes,
+	buffer_after_minutes, cancel_notice_minutes, reschedule_notice_minutes, max_reschedules, late_cancel_minutes`
+
+// Scan scans a row selected with Columns into p and derives its scope
+func Scan(row rowScanner, p *Policy) error {
+	err := row.Scan(
+		&p.ID, &p.ProviderID, &p.TeamID, &p.MinNoticeMinutes, &p.MaxHorizonDays,
+		&p.BufferBeforeMinutes, &p.BufferAfterMinutes, &p.CancelNoticeMinutes,
+		&p.RescheduleNoticeMinutes, &p.MaxReschedules, &p.LateCancelMinutes,
+	)
+	if err != nil {
+		return err
+	}
+
+	p.Scope = ScopeTeam
+	if p.ProviderID != nil {
+		p.Scope = ScopeProvider
+	}
+	return nil
+}
+
+// ForProvider resolves the policy that applies to a provider's appointments: their own,
+// else the policy of the first team they joined that has one, else the default
+func ForProvider(db Queryer, providerID string) (*Policy, error) {
+	query := `
+		SELECT bp.id, bp.user_id, bp.team_id, bp.min_notice_minutes, bp.max_horizon_days, bp.buffer_before_minutes,
+		       bp.buffer_after_minutes, bp.cancel_notice_minutes, bp.reschedule_notice_minutes,
+		       bp.max_reschedules, bp.late_cancel_minutes
+		FROM booking_policies bp
+		LEFT JOIN providers p ON p.team_id = bp.team_id AND p.user_id = $1
+		WHERE bp.user_id = $1 OR p.user_id IS NOT NULL
+		ORDER BY bp.user_id IS NULL, p.created_at ASC
+		LIMIT 1`
+
+	var p Policy
+	err := Scan(db.QueryRow(query, providerID), &p)
+	if err == sql.ErrNoRows {
+		return &Policy{Scope: ScopeDefault}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Save creates or replaces the policy of a provider (column "user_id") or team (column "team_id")
+func Save(db Queryer, column, ownerID string, settings *Policy) (*Policy, error) {
+	if column != "user_id" && column != "team_id" {
+		return nil, fmt.Errorf("invalid policy owner column %q", column)
+	}
+
+	query := `
+		INSERT INTO booking_policies (` + column + `, min_notice_minutes, max_horizon_days, buffer_before_minutes, buffer_after_minutes,
+		                              cancel_notice_minutes, reschedule_notice_minutes, max_reschedules, late_cancel_minutes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (` + column + `) WHERE ` + column + ` IS NOT NULL DO UPDATE SET
+			min_notice_minutes = EXCLUDED.min_notice_minutes,
+			max_horizon_days = EXCLUDED.max_horizon_days,
+			buffer_before_minutes = EXCLUDED.buffer_before_minutes,
+			buffer_after_minutes = EXCLUDED.buffer_after_minutes,
+			cancel_notice_minutes = EXCLUDED.cancel_notice_minutes,
+			reschedule_notice_minutes = EXCLUDED.reschedule_notice_minutes,
+			max_reschedules = EXCLUDED.max_reschedules,
+			late_cancel_minutes = EXCLUDED.late_cancel_minutes,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + Columns
+
+	var p Policy
+	err := Scan(db.QueryRow(
+		query,
+		ownerID, settings.MinNoticeMinutes, settings.MaxHorizonDays, settings.BufferBeforeMinutes,
+		settings.BufferAfterMinutes, settings.CancelNoticeMinutes, settings.RescheduleNoticeMinutes,
+		settings.MaxReschedules, settings.LateCancelMinutes, time.Now().UTC(),
+	), &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// BookingWindow returns the earliest and latest start times an appointment can be booked
+// for at now. latest is zero when the policy sets no horizon.
+func (p *Policy) BookingWindow(now time.Time) (earliest, latest time.Time) {
+	earliest = now.Add(minutes(p.MinNoticeMinutes))
+	if p.MaxHorizonDays != nil {
+		latest = now.AddDate(0, 0, *p.MaxHorizonDays)
+	}
+	return earliest, latest
+}
+
+// CheckBooking checks booking an appointment starting at start. Without a minimum notice,
+// appointments may still be recorded in the past.
+func (p *Policy) CheckBooking(start, now time.Time) *Violation {
+	earliest, latest := p.BookingWindow(now)
+	if p.MinNoticeMinutes > 0 && start.Before(earliest) {
+		return &Violation{
+			Rule:    "min_notice",
+			Message: fmt.Sprintf("Appointments must be booked at least %s in advance", formatMinutes(p.MinNoticeMinutes)),
+		}
+	}
+
+	if !latest.IsZero() && start.After(latest) {
+		return &Violation{
+			Rule:    "max_horizon",
+			Message: fmt.Sprintf("Appointments can be booked at most %d days in advance", *p.MaxHorizonDays),
+		}
+	}
+
+	return nil
+}
+
+// CheckCancellation checks cancelling an appointment starting at start. The second
+// return value reports whether the cancellation is late and should be marked as such.
+func (p *Policy) CheckCancellation(start, now time.Time) (*Violation, bool) {
+	notice := start.Sub(now)
+	if p.CancelNoticeMinutes > 0 && notice < minutes(p.CancelNoticeMinutes) {
+		return &Violation{
+			Rule:    "cancel_notice",
+			Message: fmt.Sprintf("Appointments must be cancelled at least %s before they start", formatMinutes(p.CancelNoticeMinutes)),
+		}, true
+	}
+
+	late := p.LateCancelMinutes > 0 && notice < minutes(p.LateCancelMinutes)
+	return nil, late
+}
+
+// CheckReschedule checks moving an appointment starting at start that has already been
+// rescheduled rescheduleCount times
+func (p *Policy) CheckReschedule(start, now time.Time, rescheduleCount int) *Violation {
+	if p.RescheduleNoticeMinutes > 0 && start.Sub(now) < minutes(p.RescheduleNoticeMinutes) {
+		return &Violation{
+			Rule:    "reschedule_notice",
+			Message: fmt.Sprintf("Appointments must be rescheduled at least %s before they start", formatMinutes(p.RescheduleNoticeMinutes)),
+		}
+	}
+
+	if p.MaxReschedules != nil && rescheduleCount >= *p.MaxReschedules {
+		return &Violation{
+			Rule:    "max_reschedules",
+			Message: fmt.Sprintf("Appointments can be rescheduled at most %d times", *p.MaxReschedules),
+		}
+	}
+
+	return nil
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
+
+// formatMinutes renders a notice period, e.g. "24 hours" or "90 minutes"
+func formatMinutes(n int) string {
+	switch {
+	case n%(24*60) == 0 && n/(24*60) == 1:
+		return "1 day"
+	case n%(24*60) == 0:
+		return fmt.Sprintf("%d days", n/(24*60))
+	case n%60 == 0 && n/60 == 1:
+		return "1 hour"
+	case n%60 == 0:
+		return fmt.Sprintf("%d hours", n/60)
+	default:
+		return fmt.Sprintf("%d minutes", n)
+	}
+}
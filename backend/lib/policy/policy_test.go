@@ -0,0 +1,156 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestBookingWindow(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		policy       Policy
+		wantEarliest time.Time
+		wantLatest   time.Time
+	}{
+		{"default policy", Policy{}, now, time.Time{}},
+		{"minimum notice", Policy{MinNoticeMinutes: 120}, now.Add(2 * time.Hour), time.Time{}},
+		{"horizon", Policy{MaxHorizonDays: intPtr(30)}, now, now.AddDate(0, 0, 30)},
+		{"both", Policy{MinNoticeMinutes: 24 * 60, MaxHorizonDays: intPtr(7)}, now.Add(24 * time.Hour), now.AddDate(0, 0, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earliest, latest := tt.policy.BookingWindow(now)
+			if !earliest.Equal(tt.wantEarliest) {
+				t.Errorf("earliest = %v, want %v", earliest, tt.wantEarliest)
+			}
+			if !latest.Equal(tt.wantLatest) {
+				t.Errorf("latest = %v, want %v", latest, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestCheckBooking(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		start    time.Time
+		wantRule string
+	}{
+		{"default policy allows the past", Policy{}, now.Add(-time.Hour), ""},
+		{"after the minimum notice", Policy{MinNoticeMinutes: 60}, now.Add(time.Hour), ""},
+		{"within the minimum notice", Policy{MinNoticeMinutes: 60}, now.Add(30 * time.Minute), "min_notice"},
+		{"within the horizon", Policy{MaxHorizonDays: intPtr(30)}, now.AddDate(0, 0, 30), ""},
+		{"beyond the horizon", Policy{MaxHorizonDays: intPtr(30)}, now.AddDate(0, 0, 30).Add(time.Minute), "max_horizon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rule := ruleOf(tt.policy.CheckBooking(tt.start, now)); rule != tt.wantRule {
+				t.Errorf("violation = %q, want %q", rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestCheckCancellation(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		start    time.Time
+		wantRule string
+		wantLate bool
+	}{
+		{"default policy", Policy{}, now.Add(time.Minute), "", false},
+		{"outside the notice period", Policy{CancelNoticeMinutes: 24 * 60}, now.Add(25 * time.Hour), "", false},
+		{"exactly at the notice period", Policy{CancelNoticeMinutes: 24 * 60}, now.Add(24 * time.Hour), "", false},
+		{"inside the notice period", Policy{CancelNoticeMinutes: 24 * 60}, now.Add(23 * time.Hour), "cancel_notice", true},
+		{"already started", Policy{CancelNoticeMinutes: 60}, now.Add(-time.Minute), "cancel_notice", true},
+		{"late but allowed", Policy{LateCancelMinutes: 48 * 60}, now.Add(24 * time.Hour), "", true},
+		{"not late", Policy{LateCancelMinutes: 48 * 60}, now.Add(72 * time.Hour), "", false},
+		{
+			name:     "late window wider than the notice period",
+			policy:   Policy{CancelNoticeMinutes: 60, LateCancelMinutes: 24 * 60},
+			start:    now.Add(2 * time.Hour),
+			wantRule: "",
+			wantLate: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation, late := tt.policy.CheckCancellation(tt.start, now)
+			if rule := ruleOf(violation); rule != tt.wantRule {
+				t.Errorf("violation = %q, want %q", rule, tt.wantRule)
+			}
+			if late != tt.wantLate {
+				t.Errorf("late = %v, want %v", late, tt.wantLate)
+			}
+		})
+	}
+}
+
+func TestCheckReschedule(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		policy          Policy
+		start           time.Time
+		rescheduleCount int
+		wantRule        string
+	}{
+		{"default policy", Policy{}, now.Add(time.Minute), 10, ""},
+		{"outside the notice period", Policy{RescheduleNoticeMinutes: 120}, now.Add(3 * time.Hour), 0, ""},
+		{"inside the notice period", Policy{RescheduleNoticeMinutes: 120}, now.Add(time.Hour), 0, "reschedule_notice"},
+		{"below the limit", Policy{MaxReschedules: intPtr(2)}, now.Add(time.Hour), 1, ""},
+		{"at the limit", Policy{MaxReschedules: intPtr(2)}, now.Add(time.Hour), 2, "max_reschedules"},
+		{"no reschedules allowed", Policy{MaxReschedules: intPtr(0)}, now.Add(time.Hour), 0, "max_reschedules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := tt.policy.CheckReschedule(tt.start, now, tt.rescheduleCount)
+			if rule := ruleOf(violation); rule != tt.wantRule {
+				t.Errorf("violation = %q, want %q", rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestFormatMinutes(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    string
+	}{
+		{30, "30 minutes"},
+		{90, "90 minutes"},
+		{60, "1 hour"},
+		{180, "3 hours"},
+		{24 * 60, "1 day"},
+		{3 * 24 * 60, "3 days"},
+	}
+
+	for _, tt := range tests {
+		if got := formatMinutes(tt.minutes); got != tt.want {
+			t.Errorf("formatMinutes(%d) = %q, want %q", tt.minutes, got, tt.want)
+		}
+	}
+}
+
+// ruleOf returns the violated rule, or "" when there is no violation
+func ruleOf(violation *Violation) string {
+	if violation == nil {
+		return ""
+	}
+	return violation.Rule
+}
@@ -0,0 +1,295 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxOccurrences caps how many occurrences a single expansion may produce. Rules bounded by
+// COUNT or UNTIL that exceed it are rejected; open-ended ones are cut off and extended later.
+const MaxOccurrences = 366
+
+// maxPeriods guards against rules whose periods never produce an occurrence
+const maxPeriods = 10000
+
+// DefaultHorizon bounds open-ended series (no COUNT or UNTIL) when expanding
+const DefaultHorizon = 365 * 24 * time.Hour
+
+// Rule is the subset of an RFC 5545 RRULE supported by the calendar:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL and BYDAY (weekly only)
+type Rule struct {
+	Freq     string
+	Interval int
+	Count    int        // 0 when not set
+	Until    *time.Time // nil when not set
+	ByDay    []time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse parses an RRULE string such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=12".
+// The "RRULE:" prefix is optional.
+func Parse(rrule string) (*Rule, error) {
+	rrule = strings.TrimSpace(rrule)
+	rrule = strings.TrimPrefix(rrule, "RRULE:")
+	if rrule == "" {
+		return nil, fmt.Errorf("rrule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(rrule, ";") {
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rrule part: %s", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported FREQ: %s", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL: %s", value)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT: %s", value)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value: %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "WKST":
+			if value != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rrule part: %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+
+	return rule, nil
+}
+
+// parseUntil accepts the RFC 5545 DATE and DATE-TIME forms
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL: %s", value)
+}
+
+// String formats the rule back into its canonical RRULE form
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			for code, wd := range weekdayCodes {
+				if wd == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Expand returns the start times of every occurrence beginning at dtstart, in order.
+// Expansion stops at the rule's COUNT or UNTIL, or at horizon (if non-zero). It returns an
+// error when the rule would produce more than MaxOccurrences before reaching its COUNT or
+// UNTIL; with a horizon, expansion stops at MaxOccurrences instead. Occurrences keep dtstart's
+// wall-clock time in dtstart's location, so a 09:00 series stays at 09:00 across DST changes.
+func (r *Rule) Expand(dtstart time.Time, horizon time.Time) ([]time.Time, error) {
+	if len(r.ByDay) > 0 && !containsWeekday(r.ByDay, dtstart.Weekday()) {
+		return nil, fmt.Errorf("start time falls on %s, which is not in BYDAY", dtstart.Weekday())
+	}
+	if r.Count > MaxOccurrences {
+		return nil, fmt.Errorf("COUNT cannot exceed %d occurrences", MaxOccurrences)
+	}
+
+	limit := MaxOccurrences
+	if r.Count > 0 {
+		limit = r.Count
+	}
+
+	within := func(t time.Time) bool {
+		if r.Until != nil && t.After(*r.Until) {
+			return false
+		}
+		if !horizon.IsZero() && t.After(horizon) {
+			return false
+		}
+		return true
+	}
+
+	var occurrences []time.Time
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.candidates(dtstart, period)
+		if len(candidates) == 0 {
+			// Periods can be empty (e.g. Feb 30); stop if the period start itself is out of range
+			if !within(r.periodStart(dtstart, period)) {
+				break
+			}
+			continue
+		}
+
+		for _, candidate := range candidates {
+			if candidate.Before(dtstart) {
+				continue
+			}
+			if !within(candidate) {
+				return occurrences, nil
+			}
+			if len(occurrences) == limit {
+				// Another occurrence is due; only a horizon may cut the series short
+				if r.Count == 0 && horizon.IsZero() {
+					return nil, fmt.Errorf("recurrence rule produces more than %d occurrences", MaxOccurrences)
+				}
+				return occurrences, nil
+			}
+			occurrences = append(occurrences, candidate)
+		}
+	}
+
+	return occurrences, nil
+}
+
+// periodStart returns the nominal start of the n-th period of the rule
+func (r *Rule) periodStart(dtstart time.Time, n int) time.Time {
+	step := n * r.Interval
+	switch r.Freq {
+	case "DAILY":
+		return dtstart.AddDate(0, 0, step)
+	case "WEEKLY":
+		return dtstart.AddDate(0, 0, 7*step)
+	case "MONTHLY":
+		return dtstart.AddDate(0, step, 0)
+	default:
+		return dtstart.AddDate(step, 0, 0)
+	}
+}
+
+// candidates returns the occurrences generated by the n-th period of the rule
+func (r *Rule) candidates(dtstart time.Time, n int) []time.Time {
+	step := n * r.Interval
+	hour, min, sec := dtstart.Clock()
+	loc := dtstart.Location()
+
+	switch r.Freq {
+	case "DAILY":
+		return []time.Time{dtstart.AddDate(0, 0, step)}
+
+	case "WEEKLY":
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+
+		// Weeks start on Monday (WKST=MO)
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		weekStart := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step, hour, min, sec, 0, loc)
+
+		var result []time.Time
+		for _, day := range days {
+			dayOffset := (int(day) + 6) % 7
+			result = append(result, time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day()+dayOffset, hour, min, sec, 0, loc))
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+		return result
+
+	case "MONTHLY":
+		// Months without the start day (e.g. the 31st) are skipped, per RFC 5545
+		year, month := dtstart.Year(), dtstart.Month()+time.Month(step)
+		candidate := time.Date(year, month, dtstart.Day(), hour, min, sec, 0, loc)
+		if candidate.Day() != dtstart.Day() {
+			return nil
+		}
+		return []time.Time{candidate}
+
+	default:
+		// Yearly series starting on Feb 29 only occur in leap years
+		candidate := time.Date(dtstart.Year()+step, dtstart.Month(), dtstart.Day(), hour, min, sec, 0, loc)
+		if candidate.Month() != dtstart.Month() {
+			return nil
+		}
+		return []time.Time{candidate}
+	}
+}
+
+// ShiftDays moves every BYDAY weekday by n days, keeping the rule in sync with a
+// series whose occurrences were moved to different days
+func (r *Rule) ShiftDays(n int) {
+	for i, day := range r.ByDay {
+		r.ByDay[i] = time.Weekday(((int(day)+n)%7 + 7) % 7)
+	}
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
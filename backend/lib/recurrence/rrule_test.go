@@ -0,0 +1,189 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rrule   string
+		want    string // Canonical form; empty when parsing must fail
+		wantErr string
+	}{
+		{rrule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rrule: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=12", want: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=12"},
+		{rrule: "freq=monthly;interval=2", want: "FREQ=MONTHLY;INTERVAL=2"},
+		{rrule: "FREQ=YEARLY;UNTIL=20301231", want: "FREQ=YEARLY;UNTIL=20301231T235959Z"},
+		{rrule: "FREQ=WEEKLY;UNTIL=20300101T090000Z;WKST=MO", want: "FREQ=WEEKLY;UNTIL=20300101T090000Z"},
+		{rrule: "", wantErr: "empty"},
+		{rrule: "COUNT=3", wantErr: "FREQ is required"},
+		{rrule: "FREQ=HOURLY", wantErr: "unsupported FREQ"},
+		{rrule: "FREQ=DAILY;INTERVAL=0", wantErr: "invalid INTERVAL"},
+		{rrule: "FREQ=DAILY;COUNT=0", wantErr: "invalid COUNT"},
+		{rrule: "FREQ=DAILY;COUNT=3;UNTIL=20300101", wantErr: "cannot both be set"},
+		{rrule: "FREQ=DAILY;BYDAY=MO", wantErr: "only supported with FREQ=WEEKLY"},
+		{rrule: "FREQ=WEEKLY;BYDAY=XX", wantErr: "unsupported BYDAY"},
+		{rrule: "FREQ=WEEKLY;WKST=SU", wantErr: "WKST=MO"},
+		{rrule: "FREQ=DAILY;BYHOUR=9", wantErr: "unsupported rrule part"},
+		{rrule: "FREQ=DAILY;UNTIL=tomorrow", wantErr: "invalid UNTIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rrule, func(t *testing.T) {
+			rule, err := Parse(tt.rrule)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, newYork)
+	}
+	until := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		dtstart time.Time
+		horizon time.Time
+		want    []time.Time
+		wantLen int // Checked instead of want when want is nil
+		wantErr string
+	}{
+		{
+			name:    "daily count",
+			rule:    Rule{Freq: "DAILY", Interval: 1, Count: 3},
+			dtstart: at(2026, 1, 5, 9),
+			want:    []time.Time{at(2026, 1, 5, 9), at(2026, 1, 6, 9), at(2026, 1, 7, 9)},
+		},
+		{
+			name:    "keeps the wall-clock time across DST",
+			rule:    Rule{Freq: "DAILY", Interval: 1, Count: 3},
+			dtstart: at(2026, 3, 7, 9),
+			want:    []time.Time{at(2026, 3, 7, 9), at(2026, 3, 8, 9), at(2026, 3, 9, 9)},
+		},
+		{
+			name:    "weekly by day",
+			rule:    Rule{Freq: "WEEKLY", Interval: 1, Count: 4, ByDay: []time.Weekday{time.Monday, time.Wednesday}},
+			dtstart: at(2026, 1, 7, 9),
+			want:    []time.Time{at(2026, 1, 7, 9), at(2026, 1, 12, 9), at(2026, 1, 14, 9), at(2026, 1, 19, 9)},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    Rule{Freq: "WEEKLY", Interval: 2, Until: until(at(2026, 2, 2, 9))},
+			dtstart: at(2026, 1, 5, 9),
+			want:    []time.Time{at(2026, 1, 5, 9), at(2026, 1, 19, 9), at(2026, 2, 2, 9)},
+		},
+		{
+			name:    "monthly skips months without the day",
+			rule:    Rule{Freq: "MONTHLY", Interval: 1, Count: 3},
+			dtstart: at(2026, 1, 31, 9),
+			want:    []time.Time{at(2026, 1, 31, 9), at(2026, 3, 31, 9), at(2026, 5, 31, 9)},
+		},
+		{
+			name:    "yearly on Feb 29 only in leap years",
+			rule:    Rule{Freq: "YEARLY", Interval: 1, Count: 2},
+			dtstart: at(2028, 2, 29, 9),
+			want:    []time.Time{at(2028, 2, 29, 9), at(2032, 2, 29, 9)},
+		},
+		{
+			name:    "horizon bounds open-ended rules",
+			rule:    Rule{Freq: "DAILY", Interval: 1},
+			dtstart: at(2026, 1, 5, 9),
+			horizon: at(2026, 1, 7, 9),
+			want:    []time.Time{at(2026, 1, 5, 9), at(2026, 1, 6, 9), at(2026, 1, 7, 9)},
+		},
+		{
+			name:    "open-ended rules stop at the cap",
+			rule:    Rule{Freq: "DAILY", Interval: 1},
+			dtstart: at(2026, 1, 5, 9),
+			horizon: at(2030, 1, 1, 0),
+			wantLen: MaxOccurrences,
+		},
+		{
+			name:    "count at the cap",
+			rule:    Rule{Freq: "DAILY", Interval: 1, Count: MaxOccurrences},
+			dtstart: at(2026, 1, 5, 9),
+			wantLen: MaxOccurrences,
+		},
+		{
+			name:    "count over the cap",
+			rule:    Rule{Freq: "DAILY", Interval: 1, Count: MaxOccurrences + 1},
+			dtstart: at(2026, 1, 5, 9),
+			wantErr: "COUNT cannot exceed",
+		},
+		{
+			name:    "until past the cap",
+			rule:    Rule{Freq: "DAILY", Interval: 1, Until: until(at(2028, 1, 1, 0))},
+			dtstart: at(2026, 1, 5, 9),
+			wantErr: "more than",
+		},
+		{
+			name:    "start outside BYDAY",
+			rule:    Rule{Freq: "WEEKLY", Interval: 1, Count: 2, ByDay: []time.Weekday{time.Monday}},
+			dtstart: at(2026, 1, 6, 9),
+			wantErr: "not in BYDAY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Expand(tt.dtstart, tt.horizon)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expand() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+
+			if tt.want == nil {
+				if len(got) != tt.wantLen {
+					t.Errorf("Expand() returned %d occurrences, want %d", len(got), tt.wantLen)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expand() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestShiftDays(t *testing.T) {
+	rule := Rule{Freq: "WEEKLY", ByDay: []time.Weekday{time.Monday, time.Saturday}}
+	rule.ShiftDays(2)
+	if rule.ByDay[0] != time.Wednesday || rule.ByDay[1] != time.Monday {
+		t.Errorf("ShiftDays(2) = %v, want [Wednesday Monday]", rule.ByDay)
+	}
+
+	rule.ShiftDays(-3)
+	if rule.ByDay[0] != time.Sunday || rule.ByDay[1] != time.Friday {
+		t.Errorf("ShiftDays(-3) = %v, want [Sunday Friday]", rule.ByDay)
+	}
+}
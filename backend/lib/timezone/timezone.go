@@ -0,0 +1,80 @@
+package timezone
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	// Embed the IANA database so zones resolve in minimal containers
+	_ "time/tzdata"
+)
+
+// Default is used for providers without a stored timezone
+const Default = "UTC"
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Load resolves an IANA timezone name such as "America/New_York"
+func Load(name string) (*time.Location, error) {
+	if name == "" {
+		name = Default
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", name)
+	}
+	return loc, nil
+}
+
+// ForProvider returns the timezone the provider's availability is defined in
+func ForProvider(db queryer, providerID string) (*time.Location, error) {
+	var name string
+	err := db.QueryRow(`SELECT timezone FROM users WHERE id = $1`, providerID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Load(name)
+}
+
+// Date returns the calendar date of t as seen in loc, as midnight UTC.
+// Use it to compare against DATE columns and to pick a day_of_week.
+func Date(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// WallClock returns the instant at which clocks in loc show timeOfDay ("15:04" or
+// "15:04:05") on the given calendar date. On DST transition days a time that does
+// not exist is moved forward by the length of the gap.
+func WallClock(date time.Time, timeOfDay string, loc *time.Location) (time.Time, error) {
+	tod, err := time.Parse("15:04:05", timeOfDay)
+	if err != nil {
+		tod, err = time.Parse("15:04", timeOfDay)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time format: %s", timeOfDay)
+		}
+	}
+
+	t := time.Date(date.Year(), date.Month(), date.Day(), tod.Hour(), tod.Minute(), tod.Second(), 0, loc)
+	if t.Hour() != tod.Hour() || t.Minute() != tod.Minute() {
+		// The wall-clock time falls into a DST gap; time.Date resolves it before the
+		// gap, so move it past the gap instead (02:30 becomes 03:30)
+		_, before := t.Zone()
+		_, after := t.Add(3 * time.Hour).Zone()
+		t = t.Add(time.Duration(after-before) * time.Second)
+	}
+	return t, nil
+}
+
+// StartOfDay returns midnight of the calendar date in loc and midnight of the following
+// day; the span is 23 or 25 hours long on DST transition days
+func StartOfDay(date time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	return start, time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, loc)
+}
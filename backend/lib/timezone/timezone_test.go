@@ -0,0 +1,101 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := Load(name)
+	if err != nil {
+		t.Fatalf("Load(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestLoad(t *testing.T) {
+	if loc := mustLoad(t, ""); loc.String() != Default {
+		t.Errorf("Load(\"\") = %s, want %s", loc, Default)
+	}
+	if _, err := Load("Mars/Olympus_Mons"); err == nil {
+		t.Error("Load accepted an unknown zone")
+	}
+}
+
+func TestDate(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+
+	// 20:00 UTC on Jan 5 is already Jan 6 in Tokyo
+	got := Date(time.Date(2026, 1, 5, 20, 0, 0, 0, time.UTC), tokyo)
+	want := time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("Date() = %v, want %v", got, want)
+	}
+}
+
+func TestWallClock(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		timeOfDay string
+		want      time.Time
+		wantErr   bool
+	}{
+		{"winter", utc(2026, 1, 15, 0, 0), "09:00", utc(2026, 1, 15, 14, 0), false},
+		{"summer", utc(2026, 7, 15, 0, 0), "09:00:00", utc(2026, 7, 15, 13, 0), false},
+		{"spring forward day after the gap", utc(2026, 3, 8, 0, 0), "09:00", utc(2026, 3, 8, 13, 0), false},
+		{"inside the spring forward gap", utc(2026, 3, 8, 0, 0), "02:30", utc(2026, 3, 8, 7, 30), false},
+		{"fall back day", utc(2026, 11, 1, 0, 0), "09:00", utc(2026, 11, 1, 14, 0), false},
+		{"invalid time", utc(2026, 1, 15, 0, 0), "9am", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WallClock(tt.date, tt.timeOfDay, newYork)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("WallClock() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WallClock() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("WallClock() = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name   string
+		date   time.Time
+		length time.Duration
+	}{
+		{"regular day", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), 24 * time.Hour},
+		{"spring forward", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), 23 * time.Hour},
+		{"fall back", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), 25 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := StartOfDay(tt.date, newYork)
+			if local := start.In(newYork); local.Hour() != 0 || local.Day() != tt.date.Day() {
+				t.Errorf("start = %v, want midnight on day %d", local, tt.date.Day())
+			}
+			if got := end.Sub(start); got != tt.length {
+				t.Errorf("day length = %v, want %v", got, tt.length)
+			}
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package waitlist
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/lib/conflicts"
+	"emr-calendar-backend/lib/holds"
+	"emr-calendar-backend/lib/notify"
+	"emr-calendar-backend/lib/policy"
+	"emr-calendar-backend/lib/timezone"
+
+	"github.com/google/uuid"
+)
+
+// Entry statuses. An entry waits until it is offered a slot; a declined or expired offer
+// puts it back in line, and an accepted one books it.
+const (
+	StatusWaiting   = "waiting"
+	StatusOffered   = "offered"
+	StatusBooked    = "booked"
+	StatusCancelled = "cancelled"
+)
+
+// Offer statuses
+const (
+	OfferPending   = "pending"
+	OfferAccepted  = "accepted"
+	OfferDeclined  = "declined"
+	OfferExpired   = "expired"
+	OfferWithdrawn = "withdrawn" // The patient left the waitlist while the offer was open
+)
+
+// OfferTTL is how long a patient has to accept an offer before the slot moves on to the next patient
+const OfferTTL = time.Hour
+
+// maxOfferDays limits how far ahead freed time is offered
+const maxOfferDays = 62
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Entry is a patient waiting for a slot with a provider
+type Entry struct {
+	ID                string    `json:"id" db:"id"`
+	PatientID         string    `json:"patient_id" db:"patient_id"`
+	ProviderID        string    `json:"provider_id" db:"provider_id"`
+	AppointmentTypeID *string   `json:"appointment_type_id,omitempty" db:"appointment_type_id"`
+	Duration          int       `json:"duration_minutes" db:"duration_minutes"`
+	EarliestDate      *string   `json:"earliest_date,omitempty" db:"earliest_date"` // YYYY-MM-DD; NULL = from today
+	LatestDate        *string   `json:"latest_date,omitempty" db:"latest_date"`     // YYYY-MM-DD; NULL = no limit
+	DaysOfWeek        []int     `json:"days_of_week" db:"days_of_week"`             // 0=Sunday, 6=Saturday; empty = any day
+	EarliestTime      *string   `json:"earliest_time,omitempty" db:"earliest_time"` // Provider's local time "15:04:05"; NULL = any time
+	LatestTime        *string   `json:"latest_time,omitempty" db:"latest_time"`     // Slots must end by then
+	Notes             *string   `json:"notes,omitempty" db:"notes"`
+	Status            string    `json:"status" db:"status"` // waiting, offered, booked or cancelled
+	Offer             *Offer    `json:"offer,omitempty"`    // The open offer while the entry is offered
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Offer is a freed slot offered to a waitlisted patient and held for them until it expires
+type Offer struct {
+	ID          string     `json:"id" db:"id"`
+	EntryID     string     `json:"entry_id" db:"entry_id"`
+	HoldID      *string    `json:"hold_id,omitempty" db:"hold_id"`
+	EventID     *string    `json:"event_id,omitempty" db:"event_id"` // Appointment booked by accepting the offer
+	StartTime   time.Time  `json:"start_time" db:"start_time"`
+	EndTime     time.Time  `json:"end_time" db:"end_time"`
+	Status      string     `json:"status" db:"status"` // pending, accepted, declined, expired or withdrawn
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Request is the payload for joining a provider's waitlist
+type Request struct {
+	ProviderID        string  `json:"provider_id" binding:"required"`
+	AppointmentTypeID string  `json:"appointment_type_id" binding:"required"` // Sets the slot length
+	EarliestDate      *string `json:"earliest_date" binding:"omitempty,datetime=2006-01-02"`
+	LatestDate        *string `json:"latest_date" binding:"omitempty,datetime=2006-01-02"`
+	DaysOfWeek        []int   `json:"days_of_week" binding:"omitempty,dive,min=0,max=6"`
+	EarliestTime      *string `json:"earliest_time"` // "HH:MM" in the provider's timezone
+	LatestTime        *string `json:"latest_time"`
+	Notes             *string `json:"notes"`
+}
+
+// EntryColumns lists the columns selected for every entry, in the order ScanEntry expects
+const EntryColumns = `id, patient_id, provider_id, appointment_type_id, duration_minutes, earliest_date, latest_date,
+	days_of_week, earliest_time, latest_time, notes, status, created_at, updated_at`
+
+// ScanEntry scans a row selected with EntryColumns into entry
+func ScanEntry(row rowScanner, entry *Entry) error {
+	var earliestDate, latestDate sql.NullTime
+	var daysMask int
+	err := row.Scan(
+		&entry.ID, &entry.PatientID, &entry.ProviderID, &entry.AppointmentTypeID, &entry.Duration,
+		&earliestDate, &latestDate, &daysMask, &entry.EarliestTime, &entry.LatestTime,
+		&entry.Notes, &entry.Status, &entry.CreatedAt, &entry.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	entry.EarliestDate = formatDate(earliestDate)
+	entry.LatestDate = formatDate(latestDate)
+	entry.DaysOfWeek = []int{}
+	for day := 0; day < 7; day++ {
+		if daysMask&(1<<day) != 0 {
+			entry.DaysOfWeek = append(entry.DaysOfWeek, day)
+		}
+	}
+	return nil
+}
+
+// OfferColumns lists the columns selected for every offer, in the order ScanOffer expects
+const OfferColumns = `id, entry_id, hold_id, event_id, start_time, end_time, status, expires_at, responded_at, created_at`
+
+// ScanOffer scans a row selected with OfferColumns into offer
+func ScanOffer(row rowScanner, offer *Offer) error {
+	return row.Scan(
+		&offer.ID, &offer.EntryID, &offer.HoldID, &offer.EventID, &offer.StartTime, &offer.EndTime,
+		&offer.Status, &offer.ExpiresAt, &offer.RespondedAt, &offer.CreatedAt,
+	)
+}
+
+// Create adds the patient to the end of the provider's waitlist for slots of duration minutes
+func Create(db *sql.DB, patientID string, req *Request, duration int) (*Entry, error) {
+	daysMask := 0
+	for _, day := range req.DaysOfWeek {
+		daysMask |= 1 << day
+	}
+
+	query := `
+		INSERT INTO waitlist_entries (id, patient_id, provider_id, appointment_type_id, duration_minutes, earliest_date,
+		                              latest_date, days_of_week, earliest_time, latest_time, notes, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13)
+		RETURNING ` + EntryColumns
+
+	var entry Entry
+	err := ScanEntry(db.QueryRow(
+		query,
+		uuid.New().String(), patientID, req.ProviderID, req.AppointmentTypeID, duration, req.EarliestDate,
+		req.LatestDate, daysMask, req.EarliestTime, req.LatestTime, req.Notes, StatusWaiting, time.Now().UTC(),
+	), &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// OfferFreedTime offers time freed on the provider's calendar between from and to (a cancelled
+// or deleted event, or an override opening hours) to the provider's waitlist. Waiting patients
+// are served in the order they joined: each gets the earliest free slot in the range that fits
+// their preferences, held for them until they answer or OfferTTL passes. Runs inside tx, after
+// the change that freed the time. It locks the provider's calendar before any waitlist row, so
+// callers that lock events or waitlist rows of the provider must lock the calendar first too.
+func OfferFreedTime(db *sql.DB, tx *sql.Tx, providerID string, from, to time.Time) error {
+	now := time.Now().UTC()
+
+	// Past time cannot be offered; slots after now start on the availability grid
+	aligned := false
+	if from.Before(now) {
+		from, aligned = now, true
+	}
+	if limit := now.AddDate(0, 0, maxOfferDays); to.After(limit) {
+		to = limit
+	}
+	if !from.Before(to) {
+		return nil
+	}
+
+	// Serialize with bookings so an offered slot cannot be booked at the same time
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		return err
+	}
+
+	// Offered slots are bookings, so they must start within the provider's booking window
+	bookingPolicy, err := policy.ForProvider(tx, providerID)
+	if err != nil {
+		return err
+	}
+	earliest, latest := bookingPolicy.BookingWindow(now)
+	if from.Before(earliest) {
+		from, aligned = earliest, true
+	}
+	if !latest.IsZero() && latest.Before(from) {
+		return nil
+	}
+
+	entries, err := waitingEntries(tx, providerID)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	for i := range entries {
+		start, end, found, err := findSlot(db, tx, &entries[i], from, to, latest, aligned)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := offer(tx, &entries[i], start, end, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CloseOffer ends a pending offer with status (declined, expired or withdrawn), releases its
+// hold and offers the slot to the next patient in line. The entry goes back to waiting unless
+// it has been cancelled. Call it after locking the provider's calendar.
+func CloseOffer(db *sql.DB, tx *sql.Tx, offer *Offer, providerID, status string) error {
+	now := time.Now().UTC()
+	_, err := tx.Exec(`UPDATE waitlist_offers SET status = $2, responded_at = $3 WHERE id = $1`, offer.ID, status, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE waitlist_entries SET status = $2, updated_at = $3 WHERE id = $1 AND status = $4`,
+		offer.EntryID, StatusWaiting, now, StatusOffered)
+	if err != nil {
+		return err
+	}
+
+	if offer.HoldID != nil {
+		if err := holds.Release(tx, *offer.HoldID); err != nil {
+			return err
+		}
+	}
+
+	return OfferFreedTime(db, tx, providerID, offer.StartTime, offer.EndTime)
+}
+
+// ExpireOffers expires every pending offer whose time has run out and passes its slot on. Each
+// offer is closed in its own transaction after locking its provider's calendar, so a sweep never
+// holds more than one provider's lock and a failing offer does not hold back the rest.
+func ExpireOffers(db *sql.DB) error {
+	now := time.Now().UTC()
+	rows, err := db.Query(`
+		SELECT o.id, w.provider_id
+		FROM waitlist_offers o
+		JOIN waitlist_entries w ON w.id = o.entry_id
+		WHERE o.status = $1 AND o.expires_at <= $2
+		ORDER BY o.expires_at`,
+		OfferPending, now)
+	if err != nil {
+		return err
+	}
+
+	type expiredOffer struct {
+		offerID    string
+		providerID string
+	}
+	var expired []expiredOffer
+	for rows.Next() {
+		var e expiredOffer
+		if err := rows.Scan(&e.offerID, &e.providerID); err != nil {
+			rows.Close()
+			return err
+		}
+		expired = append(expired, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range expired {
+		if err := expireOffer(db, e.offerID, e.providerID, now); err != nil {
+			log.Printf("Warning: Failed to expire waitlist offer %s: %v", e.offerID, err)
+		}
+	}
+
+	return holds.DeleteExpired(db, now)
+}
+
+// expireOffer closes one offer as expired, unless it was answered since it was listed
+func expireOffer(db *sql.DB, offerID, providerID string, now time.Time) error {
+	// Start transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := conflicts.LockProvider(tx, providerID); err != nil {
+		return err
+	}
+
+	var offer Offer
+	err = ScanOffer(tx.QueryRow(`SELECT `+OfferColumns+` FROM waitlist_offers WHERE id = $1 FOR UPDATE`, offerID), &offer)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if offer.Status != OfferPending || offer.ExpiresAt.After(now) {
+		return nil
+	}
+
+	if err := CloseOffer(db, tx, &offer, providerID, OfferExpired); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
+
+// Sweep expires unanswered offers every interval so their slots move on to the next patient.
+// It runs until the process exits.
+func Sweep(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := ExpireOffers(db); err != nil {
+			log.Printf("Warning: Failed to expire waitlist offers: %v", err)
+		}
+	}
+}
+
+// waitingEntries returns the provider's waiting entries in the order they joined
+func waitingEntries(tx *sql.Tx, providerID string) ([]Entry, error) {
+	rows, err := tx.Query(`
+		SELECT `+EntryColumns+`
+		FROM waitlist_entries
+		WHERE provider_id = $1 AND status = $2
+		ORDER BY created_at ASC
+		FOR UPDATE`,
+		providerID, StatusWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var entry Entry
+		if err := ScanEntry(rows, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// findSlot returns the earliest free slot between from and to that fits the entry's preferences
+// and was not already offered to it. Candidates follow each other back to back from the start of
+// each availability window, or from from itself when it is the start of the freed time. No slot
+// starts after latest, the end of the provider's booking window, unless it is zero.
+func findSlot(db *sql.DB, tx *sql.Tx, entry *Entry, from, to, latest time.Time, aligned bool) (time.Time, time.Time, bool, error) {
+	checker := conflicts.NewConflictChecker(db).InTx(tx)
+
+	scheduleID := ""
+	if entry.AppointmentTypeID != nil {
+		appointmentType, err := appointmenttypes.Load(tx, *entry.AppointmentTypeID)
+		if err != nil {
+			return time.Time{}, time.Time{}, false, err
+		}
+		checker.WithBuffers(appointmentType.BufferBeforeMinutes, appointmentType.BufferAfterMinutes)
+		if appointmentType.ScheduleID != nil {
+			scheduleID = *appointmentType.ScheduleID
+			checker.WithSchedule(scheduleID)
+		}
+	}
+
+	schedule, err := conflicts.LoadSchedule(tx, entry.ProviderID, scheduleID)
+	if err != nil || schedule == nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	loc := schedule.Location
+
+	declined, err := previousOffers(tx, entry.ID, from, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	startDate, endDate := timezone.Date(from, loc), timezone.Date(to, loc)
+	windowsByDate, err := checker.RangeWindows(entry.ProviderID, schedule, startDate, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	duration := time.Duration(entry.Duration) * time.Minute
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		for _, window := range windowsByDate[date.Format("2006-01-02")] {
+			start := window.Start
+			if from.After(start) {
+				if aligned {
+					start = start.Add((from.Sub(start) + duration - 1) / duration * duration)
+				} else {
+					start = from
+				}
+			}
+
+			for end := start.Add(duration); !end.After(window.End) && !end.After(to); start, end = end, end.Add(duration) {
+				if !latest.IsZero() && start.After(latest) {
+					return time.Time{}, time.Time{}, false, nil
+				}
+				if !entry.matches(start, end, loc) || overlapsAny(start, end, declined) {
+					continue
+				}
+
+				result, err := checker.CheckTimeSlotAvailability(entry.ProviderID, start, end)
+				if err != nil {
+					return time.Time{}, time.Time{}, false, err
+				}
+				if !result.HasConflict {
+					return start, end, true, nil
+				}
+			}
+		}
+	}
+
+	return time.Time{}, time.Time{}, false, nil
+}
+
+// matches reports whether a slot fits the entry's date, weekday and time-of-day preferences
+func (e *Entry) matches(start, end time.Time, loc *time.Location) bool {
+	localStart, localEnd := start.In(loc), end.In(loc)
+
+	date := localStart.Format("2006-01-02")
+	if e.EarliestDate != nil && date < *e.EarliestDate {
+		return false
+	}
+	if e.LatestDate != nil && date > *e.LatestDate {
+		return false
+	}
+
+	if len(e.DaysOfWeek) > 0 {
+		allowed := false
+		for _, day := range e.DaysOfWeek {
+			if day == int(localStart.Weekday()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if e.EarliestTime != nil && localStart.Format("15:04:05") < *e.EarliestTime {
+		return false
+	}
+	if e.LatestTime != nil && (localEnd.Format("2006-01-02") != date || localEnd.Format("15:04:05") > *e.LatestTime) {
+		return false
+	}
+	return true
+}
+
+// previousOffers returns the slots between from and to already offered to the entry and
+// turned down or left to expire, which are not offered to it again
+func previousOffers(tx *sql.Tx, entryID string, from, to time.Time) ([]conflicts.TimeWindow, error) {
+	rows, err := tx.Query(`
+		SELECT start_time, end_time
+		FROM waitlist_offers
+		WHERE entry_id = $1 AND status IN ($2, $3) AND start_time < $5 AND end_time > $4`,
+		entryID, OfferDeclined, OfferExpired, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slots []conflicts.TimeWindow
+	for rows.Next() {
+		var slot conflicts.TimeWindow
+		if err := rows.Scan(&slot.Start, &slot.End); err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, rows.Err()
+}
+
+// offer holds the slot for the entry's patient, records the offer and notifies the patient.
+// The offer expires after OfferTTL, or when the slot starts if that is sooner.
+func offer(tx *sql.Tx, entry *Entry, start, end, now time.Time) error {
+	expiresAt := now.Add(OfferTTL)
+	if start.Before(expiresAt) {
+		expiresAt = start
+	}
+
+	hold, err := holds.Place(tx, entry.ProviderID, entry.PatientID, start, end, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	offerID := uuid.New().String()
+	_, err = tx.Exec(`
+		INSERT INTO waitlist_offers (id, entry_id, hold_id, start_time, end_time, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		offerID, entry.ID, hold.ID, start, end, OfferPending, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE waitlist_entries SET status = $2, updated_at = $3 WHERE id = $1`, entry.ID, StatusOffered, now)
+	if err != nil {
+		return err
+	}
+	entry.Status = StatusOffered
+
+	var providerName string
+	if err := tx.QueryRow(`SELECT full_name FROM users WHERE id = $1`, entry.ProviderID).Scan(&providerName); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf(
+		"A slot with %s opened up on %s. It is held for you until %s.",
+		providerName, notify.FormatTime(tx, entry.PatientID, start), notify.FormatTime(tx, entry.PatientID, expiresAt),
+	)
+	return notify.Send(tx, entry.PatientID, notify.KindWaitlistOffer, "", message)
+}
+
+// overlapsAny reports whether [start, end) overlaps any of the slots
+func overlapsAny(start, end time.Time, slots []conflicts.TimeWindow) bool {
+	for _, slot := range slots {
+		if start.Before(slot.End) && end.After(slot.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDate renders a DATE column as YYYY-MM-DD, or nil when NULL
+func formatDate(date sql.NullTime) *string {
+	if !date.Valid {
+		return nil
+	}
+	formatted := date.Time.Format("2006-01-02")
+	return &formatted
+}
@@ -0,0 +1,59 @@
+package waitlist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntryMatches(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	str := func(s string) *string { return &s }
+
+	// Tuesday 2026-03-10 10:00-10:30 in New York
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, loc).UTC()
+	end := start.Add(30 * time.Minute)
+
+	tests := []struct {
+		name       string
+		entry      Entry
+		start, end time.Time
+		want       bool
+	}{
+		{"no preferences", Entry{}, start, end, true},
+		{"within date range", Entry{EarliestDate: str("2026-03-10"), LatestDate: str("2026-03-10")}, start, end, true},
+		{"before earliest date", Entry{EarliestDate: str("2026-03-11")}, start, end, false},
+		{"after latest date", Entry{LatestDate: str("2026-03-09")}, start, end, false},
+		{"allowed weekday", Entry{DaysOfWeek: []int{1, 2}}, start, end, true},
+		{"other weekday", Entry{DaysOfWeek: []int{3}}, start, end, false},
+		{"starts at earliest time", Entry{EarliestTime: str("10:00:00")}, start, end, true},
+		{"starts before earliest time", Entry{EarliestTime: str("10:15:00")}, start, end, false},
+		{"ends at latest time", Entry{LatestTime: str("10:30:00")}, start, end, true},
+		{"ends after latest time", Entry{LatestTime: str("10:15:00")}, start, end, false},
+		{
+			// 23:00 local is already the next day in UTC; the weekday and date come from the local start, not UTC
+			name:  "local date differs from UTC",
+			entry: Entry{EarliestDate: str("2026-03-10"), LatestDate: str("2026-03-10"), DaysOfWeek: []int{2}},
+			start: time.Date(2026, 3, 10, 23, 0, 0, 0, loc),
+			end:   time.Date(2026, 3, 10, 23, 30, 0, 0, loc),
+			want:  true,
+		},
+		{
+			name:  "ends past midnight",
+			entry: Entry{LatestTime: str("23:59:00")},
+			start: time.Date(2026, 3, 10, 23, 30, 0, 0, loc),
+			end:   time.Date(2026, 3, 11, 0, 30, 0, 0, loc),
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.matches(tt.start, tt.end, loc); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
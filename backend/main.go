@@ -4,12 +4,20 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"emr-calendar-backend/appointmenttypes"
+	"emr-calendar-backend/audit"
 	"emr-calendar-backend/auth"
 	"emr-calendar-backend/availability"
 	"emr-calendar-backend/config"
 	"emr-calendar-backend/database"
 	"emr-calendar-backend/events"
+	"emr-calendar-backend/lib/waitlist"
+	"emr-calendar-backend/notifications"
+	"emr-calendar-backend/policies"
+	"emr-calendar-backend/teams"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +29,12 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	// "migrate" applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrations(cfg)
+		return
+	}
+
 	// Validate Supabase configuration
 	if cfg.SupabaseURL == "" || cfg.SupabaseJWTSecret == "" || cfg.SupabaseAnonKey == "" {
 		log.Fatal("Missing required Supabase configuration. Please set SUPABASE_URL, SUPABASE_ANON_KEY, and SUPABASE_JWT_SECRET")
@@ -33,6 +47,11 @@ func main() {
 	var userHandler *auth.UserHandler
 	var eventsHandler *events.EventsHandler
 	var availabilityHandler *availability.AvailabilityHandler
+	var teamsHandler *teams.TeamsHandler
+	var appointmentTypesHandler *appointmenttypes.AppointmentTypesHandler
+	var auditHandler *audit.AuditHandler
+	var policiesHandler *policies.PoliciesHandler
+	var notificationsHandler *notifications.NotificationsHandler
 	var db *sql.DB
 	if cfg.DatabaseURL != "" {
 		var err error
@@ -42,10 +61,26 @@ func main() {
 			log.Printf("Auth proxy will work, but user profile and events endpoints will not be available")
 		} else {
 			defer db.Close()
+			if cfg.AutoMigrate {
+				if err := database.Migrate(db); err != nil {
+					log.Fatal("Failed to apply database migrations:", err)
+				}
+			}
 			userHandler = auth.NewUserHandler(db)
 			eventsHandler = events.NewEventsHandler(db)
 			availabilityHandler = availability.NewAvailabilityHandler(db)
+			teamsHandler = teams.NewTeamsHandler(db)
+			appointmentTypesHandler = appointmenttypes.NewAppointmentTypesHandler(db)
+			auditHandler = audit.NewAuditHandler(db)
+			policiesHandler = policies.NewPoliciesHandler(db)
+			notificationsHandler = notifications.NewNotificationsHandler(db)
 			log.Println("Database connected successfully")
+
+			// Expire unanswered waitlist offers and stale slot holds in the background
+			go waitlist.Sweep(db, time.Minute)
+
+			// Keep open-ended recurring series stored a year ahead
+			go events.SweepSeries(db, time.Hour)
 		}
 	} else {
 		log.Println("No DATABASE_URL provided - auth proxy will work, but user profile and events endpoints will not be available")
@@ -54,6 +89,12 @@ func main() {
 	// Setup Gin router
 	r := gin.Default()
 
+	// Only trust X-Forwarded-For from the configured proxies, so ClientIP (recorded in the
+	// audit log) cannot be forged by clients
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Add CORS middleware
 	r.Use(auth.CORSMiddleware())
 
@@ -121,20 +162,30 @@ func main() {
 		providerRoutes := apiRoutes.Group("/provider")
 		providerRoutes.Use(auth.RequireProvider())
 		{
-			providerRoutes.GET("/dashboard", auth.ProviderDashboard)
 			// Future provider endpoints will be added here
-			// providerRoutes.GET("/appointments", getProviderAppointments)
 			// providerRoutes.POST("/availability", setProviderAvailability)
+			if eventsHandler != nil {
+				providerRoutes.GET("/dashboard", eventsHandler.ProviderDashboard)
+
+				// Appointment inbox: pending requests, today's agenda and upcoming appointments
+				providerRoutes.GET("/appointments", eventsHandler.GetInbox)
+				providerRoutes.POST("/appointments/:id/approve", eventsHandler.ApproveAppointment)
+				providerRoutes.POST("/appointments/:id/decline", eventsHandler.DeclineAppointment)
+				providerRoutes.POST("/appointments/:id/propose", eventsHandler.ProposeAppointmentTime)
+			}
 		}
 
 		// Patient-only routes
 		patientRoutes := apiRoutes.Group("/patient")
 		patientRoutes.Use(auth.RequirePatient())
 		{
-			patientRoutes.GET("/dashboard", auth.PatientDashboard)
 			// Future patient endpoints will be added here
 			// patientRoutes.GET("/appointments", getPatientAppointments)
-			// patientRoutes.POST("/book", bookAppointment)
+			if eventsHandler != nil {
+				patientRoutes.GET("/dashboard", eventsHandler.PatientDashboard)
+				patientRoutes.POST("/appointments", eventsHandler.BookAppointment)
+				patientRoutes.POST("/appointments/:id/accept-proposal", eventsHandler.AcceptProposedTime)
+			}
 		}
 
 		// Events routes (only if database is connected)
@@ -143,9 +194,28 @@ func main() {
 			{
 				eventsRoutes.GET("", eventsHandler.GetEvents)
 				eventsRoutes.POST("", eventsHandler.CreateEvent)
+				eventsRoutes.GET("/deleted", auth.RequireAdmin(), eventsHandler.GetDeletedEvents)
 				eventsRoutes.GET("/:id", eventsHandler.GetEvent)
 				eventsRoutes.PATCH("/:id", eventsHandler.UpdateEvent)
 				eventsRoutes.DELETE("/:id", eventsHandler.DeleteEvent)
+				eventsRoutes.POST("/:id/restore", auth.RequireAdmin(), eventsHandler.RestoreEvent)
+
+				// Appointment lifecycle transitions
+				eventsRoutes.POST("/:id/confirm", eventsHandler.ConfirmEvent)
+				eventsRoutes.POST("/:id/check-in", eventsHandler.CheckInEvent)
+				eventsRoutes.POST("/:id/complete", eventsHandler.CompleteEvent)
+				eventsRoutes.POST("/:id/no-show", eventsHandler.NoShowEvent)
+				eventsRoutes.POST("/:id/cancel", eventsHandler.CancelEvent)
+			}
+
+			// Waitlist: patients join and answer offers; providers and admins see their tenant's entries
+			waitlistRoutes := apiRoutes.Group("/waitlist")
+			{
+				waitlistRoutes.GET("", eventsHandler.GetWaitlist)
+				waitlistRoutes.POST("", eventsHandler.JoinWaitlist)
+				waitlistRoutes.DELETE("/:id", eventsHandler.LeaveWaitlist)
+				waitlistRoutes.POST("/offers/:id/accept", eventsHandler.AcceptWaitlistOffer)
+				waitlistRoutes.POST("/offers/:id/decline", eventsHandler.DeclineWaitlistOffer)
 			}
 		}
 
@@ -155,9 +225,16 @@ func main() {
 			{
 				// Schedule-based endpoints (bulk operations)
 				availabilityRoutes.GET("/schedule", availabilityHandler.GetSchedule)
-				availabilityRoutes.POST("/schedule", availabilityHandler.CreateSchedule)
+				availabilityRoutes.POST("/schedule", availabilityHandler.CreateDefaultSchedule)
 				availabilityRoutes.PUT("/schedule", availabilityHandler.UpdateSchedule)
 
+				// Named schedule endpoints (multiple schedules per provider)
+				availabilityRoutes.GET("/schedules", availabilityHandler.GetSchedules)
+				availabilityRoutes.POST("/schedules", availabilityHandler.CreateSchedule)
+				availabilityRoutes.GET("/schedules/:scheduleId", availabilityHandler.GetScheduleByID)
+				availabilityRoutes.PUT("/schedules/:scheduleId", availabilityHandler.UpdateScheduleByID)
+				availabilityRoutes.DELETE("/schedules/:scheduleId", availabilityHandler.DeleteSchedule)
+
 				// Individual availability rule endpoints (granular control)
 				availabilityRoutes.GET("", availabilityHandler.GetAvailability)
 				availabilityRoutes.POST("", availabilityHandler.CreateAvailability)
@@ -173,6 +250,79 @@ func main() {
 			slotsRoutes := apiRoutes.Group("/slots")
 			{
 				slotsRoutes.GET("", availabilityHandler.GetSlots)
+				slotsRoutes.GET("/next", availabilityHandler.GetNextSlots)
+				slotsRoutes.POST("/hold", availabilityHandler.HoldSlot)
+				slotsRoutes.DELETE("/hold/:id", availabilityHandler.ReleaseHold)
+			}
+		}
+
+		// Audit log routes (admin only, only if database is connected)
+		if auditHandler != nil {
+			auditRoutes := apiRoutes.Group("/audit")
+			auditRoutes.Use(auth.RequireAdmin())
+			{
+				auditRoutes.GET("", auditHandler.GetAuditLog)
+			}
+		}
+
+		// Teams routes (only if database is connected)
+		if teamsHandler != nil {
+			teamsRoutes := apiRoutes.Group("/teams")
+			{
+				teamsRoutes.GET("", teamsHandler.GetTeams)
+				teamsRoutes.POST("", teamsHandler.CreateTeam)
+				teamsRoutes.GET("/:id", teamsHandler.GetTeam)
+				teamsRoutes.PATCH("/:id", teamsHandler.UpdateTeam)
+				teamsRoutes.DELETE("/:id", teamsHandler.DeleteTeam)
+
+				// Team membership endpoints
+				teamsRoutes.GET("/:id/members", teamsHandler.GetTeamMembers)
+				teamsRoutes.POST("/:id/members", teamsHandler.AddTeamMember)
+				teamsRoutes.PATCH("/:id/members/:userId", teamsHandler.UpdateTeamMember)
+				teamsRoutes.DELETE("/:id/members/:userId", teamsHandler.RemoveTeamMember)
+
+				// Team invitations: admins invite, the invited user accepts or declines
+				teamsRoutes.GET("/:id/invitations", teamsHandler.GetTeamInvitations)
+				teamsRoutes.DELETE("/:id/invitations/:userId", teamsHandler.RevokeInvitation)
+				teamsRoutes.GET("/invitations", teamsHandler.GetInvitations)
+				teamsRoutes.POST("/invitations/:id/accept", teamsHandler.AcceptInvitation)
+				teamsRoutes.POST("/invitations/:id/decline", teamsHandler.DeclineInvitation)
+
+				// Team booking policy
+				teamsRoutes.GET("/:id/policy", teamsHandler.GetTeamPolicy)
+				teamsRoutes.PUT("/:id/policy", teamsHandler.SetTeamPolicy)
+				teamsRoutes.DELETE("/:id/policy", teamsHandler.DeleteTeamPolicy)
+			}
+		}
+
+		// Notification routes (only if database is connected)
+		if notificationsHandler != nil {
+			notificationsRoutes := apiRoutes.Group("/notifications")
+			{
+				notificationsRoutes.GET("", notificationsHandler.GetNotifications)
+				notificationsRoutes.POST("/:id/read", notificationsHandler.MarkRead)
+			}
+		}
+
+		// Provider booking policy routes (only if database is connected)
+		if policiesHandler != nil {
+			policiesRoutes := apiRoutes.Group("/policies")
+			{
+				policiesRoutes.GET("", policiesHandler.GetPolicy)
+				policiesRoutes.PUT("", policiesHandler.SetPolicy)
+				policiesRoutes.DELETE("", policiesHandler.DeletePolicy)
+			}
+		}
+
+		// Appointment type routes (only if database is connected)
+		if appointmentTypesHandler != nil {
+			appointmentTypesRoutes := apiRoutes.Group("/appointment-types")
+			{
+				appointmentTypesRoutes.GET("", appointmentTypesHandler.GetAppointmentTypes)
+				appointmentTypesRoutes.POST("", appointmentTypesHandler.CreateAppointmentType)
+				appointmentTypesRoutes.GET("/:id", appointmentTypesHandler.GetAppointmentType)
+				appointmentTypesRoutes.PATCH("/:id", appointmentTypesHandler.UpdateAppointmentType)
+				appointmentTypesRoutes.DELETE("/:id", appointmentTypesHandler.DeleteAppointmentType)
 			}
 		}
 	}
@@ -182,4 +332,22 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	log.Printf("Using Supabase URL: %s", cfg.SupabaseURL)
 	log.Fatal(r.Run(port))
-}
\ No newline at end of file
+}
+
+// runMigrations connects to DATABASE_URL and applies every pending migration
+func runMigrations(cfg *config.Config) {
+	if cfg.DatabaseURL == "" {
+		log.Fatal("DATABASE_URL is required to run migrations")
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatal("Failed to apply database migrations:", err)
+	}
+	log.Println("Database migrations are up to date")
+}
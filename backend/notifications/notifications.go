@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationsHandler struct {
+	db *sql.DB
+}
+
+func NewNotificationsHandler(db *sql.DB) *NotificationsHandler {
+	return &NotificationsHandler{
+		db: db,
+	}
+}
+
+// GetNotifications lists the current user's notifications, newest first.
+// With unread=true, only notifications not yet marked as read are returned.
+func (nh *NotificationsHandler) GetNotifications(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100 // Max limit
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT id, user_id, kind, event_id, message, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1`
+	if c.Query("unread") == "true" {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC LIMIT $2 OFFSET $3"
+
+	rows, err := nh.db.Query(query, userCtx.UserID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []notify.Notification{}
+	for rows.Next() {
+		var n notify.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.EventID, &n.Message, &n.ReadAt, &n.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan notification"})
+			return
+		}
+		notifications = append(notifications, n)
+	}
+
+	var unread int
+	if err := nh.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userCtx.UserID).Scan(&unread); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread":        unread,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(notifications),
+		},
+	})
+}
+
+// MarkRead marks one of the current user's notifications as read
+func (nh *NotificationsHandler) MarkRead(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	result, err := nh.db.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, $3)
+		WHERE id = $1 AND user_id = $2`,
+		c.Param("id"), userCtx.UserID, time.Now().UTC())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify update"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
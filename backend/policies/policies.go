@@ -0,0 +1,136 @@
+package policies
+
+import (
+	"database/sql"
+	"net/http"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PoliciesHandler struct {
+	db *sql.DB
+}
+
+func NewPoliciesHandler(db *sql.DB) *PoliciesHandler {
+	return &PoliciesHandler{
+		db: db,
+	}
+}
+
+// GetPolicy returns the booking policy that applies to a provider
+// (the current user by default), whether it is their own, their team's or the default
+func (ph *PoliciesHandler) GetPolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	providerID := c.DefaultQuery("provider_id", userCtx.UserID)
+	if ok := ph.requireProviderAccess(c, userCtx, providerID); !ok {
+		return
+	}
+
+	p, err := policy.ForProvider(ph.db, providerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch policy", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": p})
+}
+
+// SetPolicy creates or replaces a provider's own policy, which takes precedence over their team's
+func (ph *PoliciesHandler) SetPolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if !userCtx.IsStaff() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only providers and admins can manage policies"})
+		return
+	}
+
+	var req policy.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	providerID := userCtx.UserID
+	if userCtx.UserRole == "admin" && req.ProviderID != nil && *req.ProviderID != "" {
+		if ok := ph.requireProviderAccess(c, userCtx, *req.ProviderID); !ok {
+			return
+		}
+		providerID = *req.ProviderID
+	}
+
+	p, err := policy.Save(ph.db, "user_id", providerID, req.Settings())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save policy", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": p})
+}
+
+// DeletePolicy removes a provider's own policy so their team's (or the default) applies again
+func (ph *PoliciesHandler) DeletePolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	if !userCtx.IsStaff() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only providers and admins can manage policies"})
+		return
+	}
+
+	providerID := userCtx.UserID
+	if userCtx.UserRole == "admin" && c.Query("provider_id") != "" {
+		if ok := ph.requireProviderAccess(c, userCtx, c.Query("provider_id")); !ok {
+			return
+		}
+		providerID = c.Query("provider_id")
+	}
+
+	result, err := ph.db.Exec(`DELETE FROM booking_policies WHERE user_id = $1`, providerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete policy"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider has no policy of their own"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Policy deleted successfully"})
+}
+
+// requireProviderAccess checks the provider is within the caller's tenant.
+// Writes the error response and returns false otherwise.
+func (ph *PoliciesHandler) requireProviderAccess(c *gin.Context, userCtx *auth.UserContext, providerID string) bool {
+	allowed, err := userCtx.CanAccessProvider(ph.db, providerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify provider access"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Provider is outside your organization"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package teams
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"emr-calendar-backend/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// invitationColumns selects an invitation with its team and invited user, in scanInvitation order
+const invitationColumns = `
+	SELECT i.id, i.team_id, t.name, i.user_id, u.full_name, u.email, i.role, i.is_visible, i.invited_by, i.created_at
+	FROM team_invitations i
+	JOIN teams t ON t.id = i.team_id
+	JOIN users u ON u.id = i.user_id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowQueryer is satisfied by both *sql.DB and *sql.Tx
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// GetInvitations lists the current user's pending team invitations
+func (th *TeamsHandler) GetInvitations(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	invitations, err := th.listInvitations(invitationColumns+` WHERE i.user_id = $1 ORDER BY i.created_at ASC`, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+// GetTeamInvitations lists a team's pending invitations (team admins only)
+func (th *TeamsHandler) GetTeamInvitations(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	invitations, err := th.listInvitations(invitationColumns+` WHERE i.team_id = $1 ORDER BY i.created_at ASC`, teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+// AcceptInvitation joins the team the current user was invited to, with the invited role
+func (th *TeamsHandler) AcceptInvitation(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Start transaction
+	tx, err := th.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the invited user can accept, and only once
+	var teamID, role string
+	var isVisible bool
+	err = tx.QueryRow(`
+		DELETE FROM team_invitations
+		WHERE id = $1 AND user_id = $2
+		RETURNING team_id, role, is_visible`,
+		c.Param("id"), userCtx.UserID).Scan(&teamID, &role, &isVisible)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	query := `
+		INSERT INTO providers (id, team_id, user_id, role, is_visible, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (team_id, user_id) DO NOTHING`
+
+	_, err = tx.Exec(query, uuid.New().String(), teamID, userCtx.UserID, role, isVisible, time.Now().UTC())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join team", "details": err.Error()})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	member, err := th.getMember(teamID, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// DeclineInvitation turns down a team invitation sent to the current user
+func (th *TeamsHandler) DeclineInvitation(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	result, err := th.db.Exec(`DELETE FROM team_invitations WHERE id = $1 AND user_id = $2`, c.Param("id"), userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify invitation"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// RevokeInvitation withdraws a pending invitation to the team (team admins only)
+func (th *TeamsHandler) RevokeInvitation(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	result, err := th.db.Exec(`DELETE FROM team_invitations WHERE team_id = $1 AND user_id = $2`, teamID, c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify invitation"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// getInvitation loads a single invitation
+func (th *TeamsHandler) getInvitation(db rowQueryer, invitationID string) (*TeamInvitation, error) {
+	var invitation TeamInvitation
+	if err := scanInvitation(db.QueryRow(invitationColumns+` WHERE i.id = $1`, invitationID), &invitation); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// listInvitations runs a query selecting invitationColumns
+func (th *TeamsHandler) listInvitations(query string, args ...interface{}) ([]TeamInvitation, error) {
+	rows, err := th.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []TeamInvitation{}
+	for rows.Next() {
+		var invitation TeamInvitation
+		if err := scanInvitation(rows, &invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// scanInvitation scans a row selected with invitationColumns
+func scanInvitation(row rowScanner, invitation *TeamInvitation) error {
+	return row.Scan(
+		&invitation.ID, &invitation.TeamID, &invitation.TeamName, &invitation.UserID, &invitation.FullName,
+		&invitation.Email, &invitation.Role, &invitation.IsVisible, &invitation.InvitedBy, &invitation.CreatedAt,
+	)
+}
@@ -0,0 +1,73 @@
+package teams
+
+import (
+	"time"
+)
+
+// Team represents a healthcare organization that groups providers
+type Team struct {
+	ID          string       `json:"id" db:"id"`
+	Name        string       `json:"name" db:"name"`
+	Description *string      `json:"description,omitempty" db:"description"`
+	Color       string       `json:"color" db:"color"`       // Hex color used by the calendar UI
+	Timezone    string       `json:"timezone" db:"timezone"` // Canonical timezone for team operations
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+	Members     []TeamMember `json:"members"`
+}
+
+// TeamMember represents a provider's membership in a team (row in the providers table)
+type TeamMember struct {
+	ID        string    `json:"id" db:"id"`
+	TeamID    string    `json:"team_id" db:"team_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	FullName  string    `json:"full_name" db:"full_name"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`             // "admin" or "member"
+	IsVisible bool      `json:"is_visible" db:"is_visible"` // Controls if their calendar appears in the team view
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TeamInvitation represents an invitation for a provider to join a team
+type TeamInvitation struct {
+	ID        string    `json:"id" db:"id"`
+	TeamID    string    `json:"team_id" db:"team_id"`
+	TeamName  string    `json:"team_name" db:"team_name"`
+	UserID    string    `json:"user_id" db:"user_id"` // Invited provider or admin
+	FullName  string    `json:"full_name" db:"full_name"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"` // Role granted on accepting
+	IsVisible bool      `json:"is_visible" db:"is_visible"`
+	InvitedBy *string   `json:"invited_by,omitempty" db:"invited_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateTeamRequest represents the request payload for creating a team
+type CreateTeamRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+	Color       string  `json:"color"`
+	Timezone    string  `json:"timezone"`
+}
+
+// UpdateTeamRequest represents the request payload for updating a team
+type UpdateTeamRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Color       *string `json:"color"`
+	Timezone    *string `json:"timezone"`
+}
+
+// AddMemberRequest represents the request payload for inviting a provider to a team
+type AddMemberRequest struct {
+	UserID    string `json:"user_id" binding:"required"`
+	Role      string `json:"role" binding:"omitempty,oneof=admin member"`
+	IsVisible *bool  `json:"is_visible"`
+}
+
+// UpdateMemberRequest represents the request payload for updating a team membership
+type UpdateMemberRequest struct {
+	Role      *string `json:"role" binding:"omitempty,oneof=admin member"`
+	IsVisible *bool   `json:"is_visible"`
+}
@@ -0,0 +1,100 @@
+package teams
+
+import (
+	"database/sql"
+	"net/http"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTeamPolicy returns the team's booking policy
+func (th *TeamsHandler) GetTeamPolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, false) {
+		return
+	}
+
+	var p policy.Policy
+	err := policy.Scan(th.db.QueryRow(`SELECT `+policy.Columns+` FROM booking_policies WHERE team_id = $1`, teamID), &p)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team has no policy"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": p})
+}
+
+// SetTeamPolicy creates or replaces the policy applied to every team member
+// without a policy of their own (team admins only)
+func (th *TeamsHandler) SetTeamPolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	var req policy.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	p, err := policy.Save(th.db, "team_id", teamID, req.Settings())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save policy", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": p})
+}
+
+// DeleteTeamPolicy removes the team's policy (team admins only)
+func (th *TeamsHandler) DeleteTeamPolicy(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	result, err := th.db.Exec(`DELETE FROM booking_policies WHERE team_id = $1`, teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete policy"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team has no policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Policy deleted successfully"})
+}
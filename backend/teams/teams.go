@@ -0,0 +1,742 @@
+package teams
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"emr-calendar-backend/auth"
+	"emr-calendar-backend/lib/notify"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const defaultTeamColor = "#6366f1"
+
+type TeamsHandler struct {
+	db *sql.DB
+}
+
+func NewTeamsHandler(db *sql.DB) *TeamsHandler {
+	return &TeamsHandler{
+		db: db,
+	}
+}
+
+// GetTeams retrieves all teams the current user belongs to
+func (th *TeamsHandler) GetTeams(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Teams are tenants: every user, including admins, only sees teams they belong to
+	query := `
+		SELECT t.id, t.name, t.description, t.color, t.timezone, t.created_at, t.updated_at
+		FROM teams t
+		JOIN providers p ON p.team_id = t.id
+		WHERE p.user_id = $1
+		ORDER BY t.name ASC`
+
+	rows, err := th.db.Query(query, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+	defer rows.Close()
+
+	teams := []Team{}
+	teamIndex := make(map[string]int)
+	for rows.Next() {
+		var team Team
+		err := rows.Scan(
+			&team.ID, &team.Name, &team.Description, &team.Color, &team.Timezone,
+			&team.CreatedAt, &team.UpdatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan team"})
+			return
+		}
+		team.Members = []TeamMember{}
+		teamIndex[team.ID] = len(teams)
+		teams = append(teams, team)
+	}
+
+	// Attach members to each team with a single query
+	if len(teams) > 0 {
+		teamIDs := make([]string, 0, len(teams))
+		for _, team := range teams {
+			teamIDs = append(teamIDs, team.ID)
+		}
+
+		members, err := th.getMembers(teamIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+			return
+		}
+
+		for _, member := range members {
+			idx := teamIndex[member.TeamID]
+			teams[idx].Members = append(teams[idx].Members, member)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"teams": teams,
+		"count": len(teams),
+	})
+}
+
+// CreateTeam creates a new team with the current user as its first admin
+func (th *TeamsHandler) CreateTeam(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+
+	// Only staff can create teams
+	if userCtx.UserRole != "provider" && userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only providers and admins can create teams"})
+		return
+	}
+
+	var req CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Team name cannot be empty"})
+		return
+	}
+
+	// Set defaults if not provided
+	if req.Color == "" {
+		req.Color = defaultTeamColor
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	// Start transaction
+	tx, err := th.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	teamID := uuid.New().String()
+	now := time.Now().UTC()
+
+	query := `
+		INSERT INTO teams (id, name, description, color, timezone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, name, description, color, timezone, created_at, updated_at`
+
+	var team Team
+	err = tx.QueryRow(
+		query,
+		teamID, strings.TrimSpace(req.Name), req.Description, req.Color, req.Timezone, now, now,
+	).Scan(
+		&team.ID, &team.Name, &team.Description, &team.Color, &team.Timezone,
+		&team.CreatedAt, &team.UpdatedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team", "details": err.Error()})
+		return
+	}
+
+	// The creator becomes the first team admin
+	memberQuery := `
+		INSERT INTO providers (id, team_id, user_id, role, is_visible, created_at, updated_at)
+		VALUES ($1, $2, $3, 'admin', true, $4, $5)`
+
+	_, err = tx.Exec(memberQuery, uuid.New().String(), teamID, userCtx.UserID, now, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add team admin", "details": err.Error()})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	members, err := th.getMembers([]string{team.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+		return
+	}
+	team.Members = members
+
+	c.JSON(http.StatusCreated, gin.H{"team": team})
+}
+
+// GetTeam retrieves a specific team with its members
+func (th *TeamsHandler) GetTeam(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, false) {
+		return
+	}
+
+	team, err := th.getTeam(teamID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// UpdateTeam updates an existing team (team admins only)
+func (th *TeamsHandler) UpdateTeam(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	var req UpdateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	// Build dynamic update query
+	updateFields := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Team name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, fmt.Sprintf("name = $%d", argIndex))
+		args = append(args, strings.TrimSpace(*req.Name))
+		argIndex++
+	}
+
+	if req.Description != nil {
+		updateFields = append(updateFields, fmt.Sprintf("description = $%d", argIndex))
+		args = append(args, req.Description)
+		argIndex++
+	}
+
+	if req.Color != nil {
+		updateFields = append(updateFields, fmt.Sprintf("color = $%d", argIndex))
+		args = append(args, *req.Color)
+		argIndex++
+	}
+
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		updateFields = append(updateFields, fmt.Sprintf("timezone = $%d", argIndex))
+		args = append(args, *req.Timezone)
+		argIndex++
+	}
+
+	if len(updateFields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	// Add updated_at field
+	updateFields = append(updateFields, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now().UTC())
+	argIndex++
+
+	args = append(args, teamID)
+	updateQuery := fmt.Sprintf(`
+		UPDATE teams
+		SET %s
+		WHERE id = $%d`,
+		strings.Join(updateFields, ", "),
+		argIndex)
+
+	result, err := th.db.Exec(updateQuery, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify update"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	team, err := th.getTeam(teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// DeleteTeam deletes a team and all of its memberships (team admins only)
+func (th *TeamsHandler) DeleteTeam(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	// Memberships are removed by ON DELETE CASCADE
+	result, err := th.db.Exec(`DELETE FROM teams WHERE id = $1`, teamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete team"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
+
+// GetTeamMembers lists all providers in a team
+func (th *TeamsHandler) GetTeamMembers(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	if !th.requireMembership(c, userCtx, teamID, false) {
+		return
+	}
+
+	members, err := th.getMembers([]string{teamID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+		"count":   len(members),
+	})
+}
+
+// AddTeamMember invites an existing provider or admin to a team (team admins only).
+// Membership grants access to the team's calendars, so the user only joins once they
+// accept the invitation through POST /teams/invitations/:id/accept.
+func (th *TeamsHandler) AddTeamMember(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	// Only providers and admins can be team members
+	var role string
+	err := th.db.QueryRow(`SELECT role FROM users WHERE id = $1`, req.UserID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	if role != "provider" && role != "admin" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only providers and admins can be added to a team"})
+		return
+	}
+
+	// Check if the user is already a member
+	existingRole, err := th.getMemberRole(teamID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing membership"})
+		return
+	}
+	if existingRole != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this team"})
+		return
+	}
+
+	// Set defaults if not provided
+	if req.Role == "" {
+		req.Role = "member"
+	}
+	isVisible := true
+	if req.IsVisible != nil {
+		isVisible = *req.IsVisible
+	}
+
+	// Start transaction so the invitation and its notification are recorded together
+	tx, err := th.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO team_invitations (id, team_id, user_id, role, is_visible, invited_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (team_id, user_id) DO NOTHING`
+
+	invitationID := uuid.New().String()
+	result, err := tx.Exec(query, invitationID, teamID, req.UserID, req.Role, isVisible, userCtx.UserID, time.Now().UTC())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite team member", "details": err.Error()})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify invitation"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has already been invited to this team"})
+		return
+	}
+
+	invitation, err := th.getInvitation(tx, invitationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return
+	}
+
+	message := fmt.Sprintf("You have been invited to join the team %s", invitation.TeamName)
+	if err = notify.Send(tx, req.UserID, notify.KindTeamInvitation, "", message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify invited user"})
+		return
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation})
+}
+
+// UpdateTeamMember changes a member's role or visibility (team admins only)
+func (th *TeamsHandler) UpdateTeamMember(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+	memberUserID := c.Param("userId")
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !th.requireMembership(c, userCtx, teamID, true) {
+		return
+	}
+
+	existingRole, err := th.getMemberRole(teamID, memberUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team member"})
+		return
+	}
+	if existingRole == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team member not found"})
+		return
+	}
+
+	// A team must always keep at least one admin
+	if req.Role != nil && *req.Role != "admin" && existingRole == "admin" {
+		isLast, err := th.isLastAdmin(teamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check team admins"})
+			return
+		}
+		if isLast {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot demote the last team admin"})
+			return
+		}
+	}
+
+	// Build dynamic update query
+	updateFields := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Role != nil {
+		updateFields = append(updateFields, fmt.Sprintf("role = $%d", argIndex))
+		args = append(args, *req.Role)
+		argIndex++
+	}
+
+	if req.IsVisible != nil {
+		updateFields = append(updateFields, fmt.Sprintf("is_visible = $%d", argIndex))
+		args = append(args, *req.IsVisible)
+		argIndex++
+	}
+
+	if len(updateFields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	// Add updated_at field
+	updateFields = append(updateFields, fmt.Sprintf("updated_at = $%d", argIndex))
+	args = append(args, time.Now().UTC())
+	argIndex++
+
+	args = append(args, teamID, memberUserID)
+	updateQuery := fmt.Sprintf(`
+		UPDATE providers
+		SET %s
+		WHERE team_id = $%d AND user_id = $%d`,
+		strings.Join(updateFields, ", "),
+		argIndex, argIndex+1)
+
+	if _, err := th.db.Exec(updateQuery, args...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team member"})
+		return
+	}
+
+	member, err := th.getMember(teamID, memberUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RemoveTeamMember removes a provider from a team (team admins, or the member themselves)
+func (th *TeamsHandler) RemoveTeamMember(c *gin.Context) {
+	userCtx, exists := auth.GetUserContext(c)
+	if !exists || userCtx == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User context not found"})
+		return
+	}
+	teamID := c.Param("id")
+	memberUserID := c.Param("userId")
+
+	// Members may leave a team on their own; removing others requires team admin
+	requireAdmin := memberUserID != userCtx.UserID
+	if !th.requireMembership(c, userCtx, teamID, requireAdmin) {
+		return
+	}
+
+	existingRole, err := th.getMemberRole(teamID, memberUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team member"})
+		return
+	}
+	if existingRole == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team member not found"})
+		return
+	}
+
+	// A team must always keep at least one admin
+	if existingRole == "admin" {
+		isLast, err := th.isLastAdmin(teamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check team admins"})
+			return
+		}
+		if isLast {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove the last team admin"})
+			return
+		}
+	}
+
+	query := `DELETE FROM providers WHERE team_id = $1 AND user_id = $2`
+	if _, err := th.db.Exec(query, teamID, memberUserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove team member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
+
+// requireMembership verifies the current user may access the team, writing an error response if not.
+// When adminOnly is set the user must be a team admin, or a system admin who belongs to the team.
+func (th *TeamsHandler) requireMembership(c *gin.Context, userCtx *auth.UserContext, teamID string, adminOnly bool) bool {
+	role, err := th.getMemberRole(teamID, userCtx.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check team membership"})
+		return false
+	}
+
+	// Hide the team entirely from non-members
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return false
+	}
+
+	if adminOnly && role != "admin" && userCtx.UserRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Team admin role required"})
+		return false
+	}
+
+	return true
+}
+
+// getMemberRole returns the user's role in the team, or an empty string if they are not a member
+func (th *TeamsHandler) getMemberRole(teamID, userID string) (string, error) {
+	var role string
+	query := `SELECT role FROM providers WHERE team_id = $1 AND user_id = $2`
+	err := th.db.QueryRow(query, teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// isLastAdmin reports whether the team has exactly one admin left
+func (th *TeamsHandler) isLastAdmin(teamID string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM providers WHERE team_id = $1 AND role = 'admin'`
+	if err := th.db.QueryRow(query, teamID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count <= 1, nil
+}
+
+// getTeam loads a team together with its members
+func (th *TeamsHandler) getTeam(teamID string) (*Team, error) {
+	query := `
+		SELECT id, name, description, color, timezone, created_at, updated_at
+		FROM teams
+		WHERE id = $1`
+
+	team := &Team{}
+	err := th.db.QueryRow(query, teamID).Scan(
+		&team.ID, &team.Name, &team.Description, &team.Color, &team.Timezone,
+		&team.CreatedAt, &team.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := th.getMembers([]string{teamID})
+	if err != nil {
+		return nil, err
+	}
+	team.Members = members
+
+	return team, nil
+}
+
+// getMember loads a single team membership
+func (th *TeamsHandler) getMember(teamID, userID string) (*TeamMember, error) {
+	query := `
+		SELECT p.id, p.team_id, p.user_id, u.full_name, u.email, p.role, p.is_visible, p.created_at, p.updated_at
+		FROM providers p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.team_id = $1 AND p.user_id = $2`
+
+	member := &TeamMember{}
+	err := th.db.QueryRow(query, teamID, userID).Scan(
+		&member.ID, &member.TeamID, &member.UserID, &member.FullName, &member.Email,
+		&member.Role, &member.IsVisible, &member.CreatedAt, &member.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// getMembers loads the members of the given teams
+func (th *TeamsHandler) getMembers(teamIDs []string) ([]TeamMember, error) {
+	query := `
+		SELECT p.id, p.team_id, p.user_id, u.full_name, u.email, p.role, p.is_visible, p.created_at, p.updated_at
+		FROM providers p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.team_id = ANY($1)
+		ORDER BY u.full_name ASC`
+
+	rows, err := th.db.Query(query, pq.Array(teamIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []TeamMember{}
+	for rows.Next() {
+		var member TeamMember
+		err := rows.Scan(
+			&member.ID, &member.TeamID, &member.UserID, &member.FullName, &member.Email,
+			&member.Role, &member.IsVisible, &member.CreatedAt, &member.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}